@@ -0,0 +1,268 @@
+// Package bsii contains a decoder for the binary SII format (BSII)
+// used in save games and some definition files of Euro Truck
+// Simulator 2 / American Truck Simulator. Decoded files are written
+// as canonical textual SII units.
+package bsii
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	maxSupportedVersion = 3
+
+	namelessID = 0xff
+
+	// maxPreallocOrdinals limits the space reserved for the ordinals
+	// of a field as their count is read from the untrusted file
+	maxPreallocOrdinals = 256
+)
+
+type (
+	// Decoder reads BSII data and writes the textual representation
+	Decoder struct {
+		r       *bufio.Reader
+		version uint32
+
+		structs map[uint32]*structure
+	}
+
+	field struct {
+		Name      string
+		ValueType valueType
+		Ordinals  map[uint32]string
+	}
+
+	structure struct {
+		ID     uint32
+		Name   string
+		Fields []field
+	}
+
+	header struct {
+		Signature [4]byte
+		Version   uint32
+	}
+)
+
+var (
+	bsiiMagic = []byte("BSII")
+
+	// ErrInvalidData signals the BSII data is malformed
+	ErrInvalidData = errors.New("invalid BSII data")
+)
+
+// IsBinary reports whether the given data starts with the BSII
+// signature
+func IsBinary(data []byte) bool {
+	return bytes.HasPrefix(data, bsiiMagic)
+}
+
+// Decode converts the given BSII data into its textual representation
+func Decode(data []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := NewDecoder(bytes.NewReader(data)).Decode(buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// NewDecoder creates a Decoder reading BSII data from r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r:       bufio.NewReader(r),
+		structs: make(map[uint32]*structure),
+	}
+}
+
+// Decode reads the whole BSII file and writes the textual SII
+// representation to w
+func (d *Decoder) Decode(w io.Writer) (err error) {
+	var hdr header
+	if err = d.read(&hdr); err != nil {
+		return fmt.Errorf("reading header: %w", err)
+	}
+
+	if !bytes.Equal(hdr.Signature[:], bsiiMagic) {
+		return fmt.Errorf("unexpected signature: %w", ErrInvalidData)
+	}
+
+	if hdr.Version == 0 || hdr.Version > maxSupportedVersion {
+		return fmt.Errorf("unsupported version %d: %w", hdr.Version, ErrInvalidData)
+	}
+	d.version = hdr.Version
+
+	bw := bufio.NewWriter(w)
+	if _, err = bw.WriteString("SiiNunit\n{\n"); err != nil {
+		return fmt.Errorf("writing preamble: %w", err)
+	}
+
+	for {
+		var blockType uint32
+		if err = d.read(&blockType); err != nil {
+			return fmt.Errorf("reading block type: %w", err)
+		}
+
+		if blockType == 0 {
+			var valid bool
+			if valid, err = d.readStructure(); err != nil {
+				return fmt.Errorf("reading structure definition: %w", err)
+			}
+
+			if !valid {
+				// End-of-file marker
+				break
+			}
+
+			continue
+		}
+
+		s, ok := d.structs[blockType]
+		if !ok {
+			return fmt.Errorf("data block for unknown structure %d: %w", blockType, ErrInvalidData)
+		}
+
+		if err = d.writeUnit(bw, s); err != nil {
+			return fmt.Errorf("reading %s unit: %w", s.Name, err)
+		}
+	}
+
+	if _, err = bw.WriteString("}\n"); err != nil {
+		return fmt.Errorf("writing epilogue: %w", err)
+	}
+
+	if err = bw.Flush(); err != nil {
+		return fmt.Errorf("flushing output: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Decoder) read(v any) error {
+	if err := binary.Read(d.r, binary.LittleEndian, v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("unexpected end of data: %w", ErrInvalidData)
+		}
+		return fmt.Errorf("reading data: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Decoder) readString() (string, error) {
+	var length uint32
+	if err := d.read(&length); err != nil {
+		return "", fmt.Errorf("reading string length: %w", err)
+	}
+
+	// The length is read from the file, the buffer only grows with the
+	// data actually available
+	buf := new(bytes.Buffer)
+	if _, err := io.CopyN(buf, d.r, int64(length)); err != nil {
+		return "", fmt.Errorf("reading string: %w", ErrInvalidData)
+	}
+
+	return buf.String(), nil
+}
+
+// readStructure reads a structure definition block and reports
+// whether the block was valid. An invalid block marks the end of
+// the file.
+func (d *Decoder) readStructure() (bool, error) {
+	var valid bool
+	if err := d.read(&valid); err != nil {
+		return false, fmt.Errorf("reading validity: %w", err)
+	}
+
+	if !valid {
+		return false, nil
+	}
+
+	var (
+		s   structure
+		err error
+	)
+
+	if err = d.read(&s.ID); err != nil {
+		return false, fmt.Errorf("reading structure id: %w", err)
+	}
+
+	if s.Name, err = d.readString(); err != nil {
+		return false, fmt.Errorf("reading structure name: %w", err)
+	}
+
+	for {
+		var f field
+		if err = d.read(&f.ValueType); err != nil {
+			return false, fmt.Errorf("reading value type: %w", err)
+		}
+
+		if f.ValueType == 0 {
+			// End of field list
+			break
+		}
+
+		if f.Name, err = d.readString(); err != nil {
+			return false, fmt.Errorf("reading field name: %w", err)
+		}
+
+		if f.ValueType == typeOrdinalString {
+			if f.Ordinals, err = d.readOrdinals(); err != nil {
+				return false, fmt.Errorf("reading ordinals for %s: %w", f.Name, err)
+			}
+		}
+
+		s.Fields = append(s.Fields, f)
+	}
+
+	d.structs[s.ID] = &s
+	return true, nil
+}
+
+func (d *Decoder) readOrdinals() (map[uint32]string, error) {
+	var count uint32
+	if err := d.read(&count); err != nil {
+		return nil, fmt.Errorf("reading count: %w", err)
+	}
+
+	ordinals := make(map[uint32]string, min(count, maxPreallocOrdinals))
+	for i := uint32(0); i < count; i++ {
+		var ordinal uint32
+		if err := d.read(&ordinal); err != nil {
+			return nil, fmt.Errorf("reading ordinal: %w", err)
+		}
+
+		v, err := d.readString()
+		if err != nil {
+			return nil, fmt.Errorf("reading ordinal value: %w", err)
+		}
+
+		ordinals[ordinal] = v
+	}
+
+	return ordinals, nil
+}
+
+func (d *Decoder) writeUnit(w *bufio.Writer, s *structure) error {
+	id, err := d.readID()
+	if err != nil {
+		return fmt.Errorf("reading unit id: %w", err)
+	}
+
+	fmt.Fprintf(w, "%s : %s {\n", s.Name, id)
+
+	for _, f := range s.Fields {
+		if err = d.writeField(w, f); err != nil {
+			return fmt.Errorf("reading field %s: %w", f.Name, err)
+		}
+	}
+
+	_, err = w.WriteString("}\n\n")
+	return err //nolint:wrapcheck
+}
@@ -0,0 +1,175 @@
+package bsii
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"math"
+	"os"
+	"path"
+	"runtime"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+type testEncoder struct{ bytes.Buffer }
+
+func (e *testEncoder) put(v ...any) *testEncoder {
+	for _, x := range v {
+		if s, ok := x.(string); ok {
+			binary.Write(e, binary.LittleEndian, uint32(len(s))) //nolint:errcheck,gosec
+			e.WriteString(s)
+			continue
+		}
+
+		binary.Write(e, binary.LittleEndian, x) //nolint:errcheck,gosec
+	}
+	return e
+}
+
+func encodeToken(s string) (v uint64) {
+	for i := len(s) - 1; i >= 0; i-- {
+		v = v*uint64(len(tokenChars)+1) + uint64(bytes.IndexByte([]byte(tokenChars), s[i])+1)
+	}
+	return v
+}
+
+// buildTestFile creates a BSII file containing a single unit with a
+// single field of the given type
+func buildTestFile(version uint32, t valueType, value func(*testEncoder), ordinals ...string) []byte {
+	e := new(testEncoder)
+	e.put([4]byte{'B', 'S', 'I', 'I'}, version)
+
+	// Structure definition
+	e.put(uint32(0), true, uint32(1), "test_unit", uint32(t), "value")
+	if t == typeOrdinalString {
+		e.put(uint32(len(ordinals)))
+		for i, o := range ordinals {
+			e.put(uint32(i), o)
+		}
+	}
+	e.put(uint32(0))
+
+	// Data block with named ID
+	e.put(uint32(1), uint8(2), encodeToken("test"), encodeToken("unit"))
+	value(e)
+
+	// End of file
+	e.put(uint32(0), false)
+
+	return e.Bytes()
+}
+
+func TestDecodeValueTypes(t *testing.T) {
+	for name, tc := range map[string]struct {
+		version  uint32
+		t        valueType
+		value    func(*testEncoder)
+		ordinals []string
+	}{
+		"bool":            {t: typeBool, value: func(e *testEncoder) { e.put(true) }},
+		"bool_array":      {t: typeBoolArray, value: func(e *testEncoder) { e.put(uint32(2), true, false) }},
+		"float":           {t: typeFloat, value: func(e *testEncoder) { e.put(float32(0.5)) }},
+		"float_array":     {t: typeFloatArray, value: func(e *testEncoder) { e.put(uint32(2), float32(1), float32(-2.25)) }},
+		"id_array":        {t: typeIDArray, value: func(e *testEncoder) { e.put(uint32(2), uint8(0), uint8(namelessID), uint64(0x1f42ba0)) }},
+		"id_named":        {t: typeID, value: func(e *testEncoder) { e.put(uint8(2), encodeToken("company"), encodeToken("volvo")) }},
+		"id_nameless":     {t: typeIDNullable, value: func(e *testEncoder) { e.put(uint8(namelessID), uint64(0x1f42ba0)) }},
+		"int16":           {t: typeInt16, value: func(e *testEncoder) { e.put(int16(-16)) }},
+		"int16_array":     {t: typeInt16Array, value: func(e *testEncoder) { e.put(uint32(1), int16(16)) }},
+		"int32":           {t: typeInt32, value: func(e *testEncoder) { e.put(int32(-1)) }},
+		"int32_array":     {t: typeInt32Array, value: func(e *testEncoder) { e.put(uint32(0)) }},
+		"int64":           {t: typeInt64, value: func(e *testEncoder) { e.put(int64(math.MinInt64)) }},
+		"int64_array":     {t: typeInt64Array, value: func(e *testEncoder) { e.put(uint32(1), int64(42)) }},
+		"ordinal":         {t: typeOrdinalString, value: func(e *testEncoder) { e.put(uint32(1)) }, ordinals: []string{"none", "running"}},
+		"placement_v1":    {version: 1, t: typePlacement, value: func(e *testEncoder) { e.put([4]float32{1, 2, 3, 0}, [4]float32{1, 0, 0, 0}) }},
+		"placement":       {t: typePlacement, value: func(e *testEncoder) { e.put([3]float32{1, 2, 3}, int32(0x801801), [4]float32{1, 0, 0.5, 0}) }},
+		"placement_array": {t: typePlacementArray, value: func(e *testEncoder) { e.put(uint32(1), [3]float32{1, 2, 3}, int32(0), [4]float32{1, 0, 0, 0}) }},
+		"string":          {t: typeString, value: func(e *testEncoder) { e.put("Hello \"World\"") }},
+		"string_array":    {t: typeStringArray, value: func(e *testEncoder) { e.put(uint32(2), "a", "") }},
+		"token":           {t: typeToken, value: func(e *testEncoder) { e.put(encodeToken("scania_r")) }},
+		"token_array":     {t: typeTokenArray, value: func(e *testEncoder) { e.put(uint32(2), encodeToken("a_1"), uint64(0)) }},
+		"uint16":          {t: typeUint16, value: func(e *testEncoder) { e.put(uint16(math.MaxUint16)) }},
+		"uint16_array":    {t: typeUint16Array, value: func(e *testEncoder) { e.put(uint32(1), uint16(1)) }},
+		"uint32":          {t: typeUint32, value: func(e *testEncoder) { e.put(uint32(math.MaxUint32)) }},
+		"uint32_alt":      {t: typeUint32Alt, value: func(e *testEncoder) { e.put(uint32(7)) }},
+		"uint32_array":    {t: typeUint32Array, value: func(e *testEncoder) { e.put(uint32(1), uint32(3)) }},
+		"uint64":          {t: typeUint64, value: func(e *testEncoder) { e.put(uint64(math.MaxUint64)) }},
+		"uint64_array":    {t: typeUint64Array, value: func(e *testEncoder) { e.put(uint32(1), uint64(3)) }},
+		"vec2f":           {t: typeVec2f, value: func(e *testEncoder) { e.put([2]float32{1, 0.1}) }},
+		"vec2f_array":     {t: typeVec2fArray, value: func(e *testEncoder) { e.put(uint32(1), [2]float32{1, 2}) }},
+		"vec3f":           {t: typeVec3f, value: func(e *testEncoder) { e.put([3]float32{1, 2, 3}) }},
+		"vec3f_array":     {t: typeVec3fArray, value: func(e *testEncoder) { e.put(uint32(1), [3]float32{-1, -2, -3}) }},
+		"vec3i":           {t: typeVec3i, value: func(e *testEncoder) { e.put([3]int32{1, -2, 3}) }},
+		"vec3i_array":     {t: typeVec3iArray, value: func(e *testEncoder) { e.put(uint32(1), [3]int32{4, 5, 6}) }},
+		"vec4f":           {t: typeVec4f, value: func(e *testEncoder) { e.put([4]float32{1, 2, 3, 4}) }},
+		"vec4f_array":     {t: typeVec4fArray, value: func(e *testEncoder) { e.put(uint32(1), [4]float32{0, 0, 0, 1}) }},
+	} {
+		t.Run(name, func(t *testing.T) {
+			version := tc.version
+			if version == 0 {
+				version = maxSupportedVersion
+			}
+
+			out, err := Decode(buildTestFile(version, tc.t, tc.value, tc.ordinals...))
+			if err != nil {
+				t.Fatalf("decoding: %s", err)
+			}
+
+			golden := path.Join("testdata", name+".sii")
+			if *updateGolden {
+				if err = os.WriteFile(golden, out, 0o644); err != nil { //#nosec:G306 // Test fixture
+					t.Fatalf("updating golden file: %s", err)
+				}
+			}
+
+			expect, err := os.ReadFile(golden) //#nosec:G304 // Test fixture
+			if err != nil {
+				t.Fatalf("reading golden file: %s", err)
+			}
+
+			if !bytes.Equal(out, expect) {
+				t.Errorf("unexpected output:\n%s\nexpected:\n%s", out, expect)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	valid := buildTestFile(maxSupportedVersion, typeInt32, func(e *testEncoder) { e.put(int32(1)) })
+
+	for name, data := range map[string][]byte{
+		"empty":       nil,
+		"signature":   append([]byte("XSII"), valid[4:]...),
+		"truncated":   valid[:len(valid)-3],
+		"version":     append([]byte("BSII\x09\x00\x00\x00"), valid[8:]...),
+		"value-types": buildTestFile(maxSupportedVersion, valueType(0xff), func(*testEncoder) {}),
+	} {
+		if _, err := Decode(data); err == nil {
+			t.Errorf("invalid data %q did not cause an error", name)
+		}
+	}
+}
+
+func TestDecodeOversizedLengths(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"string": new(testEncoder).put([4]byte{'B', 'S', 'I', 'I'}, uint32(maxSupportedVersion),
+			uint32(0), true, uint32(1), uint32(math.MaxUint32), "short").Bytes(),
+		"ordinals": new(testEncoder).put([4]byte{'B', 'S', 'I', 'I'}, uint32(maxSupportedVersion),
+			uint32(0), true, uint32(1), "test_unit", uint32(typeOrdinalString), "value", uint32(math.MaxUint32)).Bytes(),
+	} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+
+		if _, err := Decode(data); !errors.Is(err, ErrInvalidData) {
+			t.Errorf("%s: expected invalid data error, got %v", name, err)
+		}
+
+		// Lengths read from the file must not be allocated up front
+		runtime.ReadMemStats(&after)
+		if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 1<<20 {
+			t.Errorf("%s: decoding allocated %d bytes", name, alloc)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package bsii
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	typeString          valueType = 0x01
+	typeStringArray     valueType = 0x02
+	typeToken           valueType = 0x03
+	typeTokenArray      valueType = 0x04
+	typeFloat           valueType = 0x05
+	typeFloatArray      valueType = 0x06
+	typeVec2f           valueType = 0x07
+	typeVec2fArray      valueType = 0x08
+	typeVec3f           valueType = 0x09
+	typeVec3fArray      valueType = 0x0a
+	typeVec3i           valueType = 0x11
+	typeVec3iArray      valueType = 0x12
+	typeVec4f           valueType = 0x17
+	typeVec4fArray      valueType = 0x18
+	typePlacement       valueType = 0x19
+	typePlacementArray  valueType = 0x1a
+	typeInt32           valueType = 0x25
+	typeInt32Array      valueType = 0x26
+	typeUint32          valueType = 0x27
+	typeUint32Array     valueType = 0x28
+	typeInt16           valueType = 0x29
+	typeInt16Array      valueType = 0x2a
+	typeUint16          valueType = 0x2b
+	typeUint16Array     valueType = 0x2c
+	typeUint32Alt       valueType = 0x2f
+	typeInt64           valueType = 0x31
+	typeInt64Array      valueType = 0x32
+	typeUint64          valueType = 0x33
+	typeUint64Array     valueType = 0x34
+	typeBool            valueType = 0x35
+	typeBoolArray       valueType = 0x36
+	typeOrdinalString   valueType = 0x37
+	typeID              valueType = 0x39
+	typeIDArray         valueType = 0x3a
+	typeIDAlt           valueType = 0x3b
+	typeIDAltArray      valueType = 0x3c
+	typeIDNullable      valueType = 0x3d
+	typeIDNullableArray valueType = 0x3e
+)
+
+const (
+	placementBiasMask   = 0xfff
+	placementBiasOffset = 2048
+	placementBiasShift  = 9
+	placementBiasZShift = 12
+	tokenChars          = "0123456789abcdefghijklmnopqrstuvwxyz_"
+	maxIntegralFloat    = 1e7
+	namelessPartBits    = 16
+	namelessPartMask    = 0xffff
+	uint64Bits          = 64
+)
+
+type valueType uint32
+
+// arrayTypes maps array value types to the type of their elements
+var arrayTypes = map[valueType]valueType{
+	typeStringArray:     typeString,
+	typeTokenArray:      typeToken,
+	typeFloatArray:      typeFloat,
+	typeVec2fArray:      typeVec2f,
+	typeVec3fArray:      typeVec3f,
+	typeVec3iArray:      typeVec3i,
+	typeVec4fArray:      typeVec4f,
+	typePlacementArray:  typePlacement,
+	typeInt32Array:      typeInt32,
+	typeUint32Array:     typeUint32,
+	typeInt16Array:      typeInt16,
+	typeUint16Array:     typeUint16,
+	typeInt64Array:      typeInt64,
+	typeUint64Array:     typeUint64,
+	typeBoolArray:       typeBool,
+	typeIDArray:         typeID,
+	typeIDAltArray:      typeIDAlt,
+	typeIDNullableArray: typeIDNullable,
+}
+
+func (d *Decoder) writeField(w *bufio.Writer, f field) error {
+	elemType, isArray := arrayTypes[f.ValueType]
+	if !isArray {
+		v, err := d.readValue(f.ValueType, f)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, " %s: %s\n", f.Name, v)
+		return nil
+	}
+
+	var count uint32
+	if err := d.read(&count); err != nil {
+		return fmt.Errorf("reading array length: %w", err)
+	}
+
+	fmt.Fprintf(w, " %s: %d\n", f.Name, count)
+
+	for i := uint32(0); i < count; i++ {
+		v, err := d.readValue(elemType, f)
+		if err != nil {
+			return fmt.Errorf("reading element %d: %w", i, err)
+		}
+
+		fmt.Fprintf(w, " %s[%d]: %s\n", f.Name, i, v)
+	}
+
+	return nil
+}
+
+//nolint:funlen,gocyclo // Big switch over all known value types
+func (d *Decoder) readValue(t valueType, f field) (string, error) {
+	switch t {
+	case typeString:
+		s, err := d.readString()
+		return formatString(s), err
+
+	case typeToken:
+		var v uint64
+		err := d.read(&v)
+		return formatToken(decodeToken(v)), err
+
+	case typeFloat:
+		var v float32
+		err := d.read(&v)
+		return formatFloat(v), err
+
+	case typeVec2f:
+		var v [2]float32
+		err := d.read(&v)
+		return formatFloats(v[:]), err
+
+	case typeVec3f:
+		var v [3]float32
+		err := d.read(&v)
+		return formatFloats(v[:]), err
+
+	case typeVec4f:
+		var v [4]float32
+		err := d.read(&v)
+		return formatFloats(v[:]), err
+
+	case typeVec3i:
+		var v [3]int32
+		err := d.read(&v)
+		return fmt.Sprintf("(%d, %d, %d)", v[0], v[1], v[2]), err
+
+	case typePlacement:
+		return d.readPlacement()
+
+	case typeInt32:
+		var v int32
+		err := d.read(&v)
+		return strconv.FormatInt(int64(v), 10), err
+
+	case typeUint32, typeUint32Alt:
+		var v uint32
+		err := d.read(&v)
+		return strconv.FormatUint(uint64(v), 10), err
+
+	case typeInt16:
+		var v int16
+		err := d.read(&v)
+		return strconv.FormatInt(int64(v), 10), err
+
+	case typeUint16:
+		var v uint16
+		err := d.read(&v)
+		return strconv.FormatUint(uint64(v), 10), err
+
+	case typeInt64:
+		var v int64
+		err := d.read(&v)
+		return strconv.FormatInt(v, 10), err
+
+	case typeUint64:
+		var v uint64
+		err := d.read(&v)
+		return strconv.FormatUint(v, 10), err
+
+	case typeBool:
+		var v bool
+		err := d.read(&v)
+		return strconv.FormatBool(v), err
+
+	case typeOrdinalString:
+		var v uint32
+		if err := d.read(&v); err != nil {
+			return "", err
+		}
+
+		s, ok := f.Ordinals[v]
+		if !ok {
+			return "", fmt.Errorf("unknown ordinal %d: %w", v, ErrInvalidData)
+		}
+		return formatToken(s), nil
+
+	case typeID, typeIDAlt, typeIDNullable:
+		return d.readID()
+
+	default:
+		return "", fmt.Errorf("unknown value type 0x%x: %w", uint32(t), ErrInvalidData)
+	}
+}
+
+// readID reads an unit ID which is either a list of tokens or a
+// nameless ID represented by a single number
+func (d *Decoder) readID() (string, error) {
+	var parts uint8
+	if err := d.read(&parts); err != nil {
+		return "", fmt.Errorf("reading id length: %w", err)
+	}
+
+	switch parts {
+	case 0:
+		return "null", nil
+
+	case namelessID:
+		var v uint64
+		if err := d.read(&v); err != nil {
+			return "", fmt.Errorf("reading nameless id: %w", err)
+		}
+		return formatNameless(v), nil
+	}
+
+	tokens := make([]uint64, parts)
+	if err := d.read(tokens); err != nil {
+		return "", fmt.Errorf("reading id parts: %w", err)
+	}
+
+	names := make([]string, len(tokens))
+	for i, t := range tokens {
+		names[i] = decodeToken(t)
+	}
+
+	return strings.Join(names, "."), nil
+}
+
+// readPlacement reads a position and rotation. Starting with version 2
+// the fourth value is a bias for very large X and Z coordinates.
+func (d *Decoder) readPlacement() (string, error) {
+	var (
+		pos  [3]float32
+		bias int32
+		rot  [4]float32
+	)
+
+	if err := d.read(&pos); err != nil {
+		return "", fmt.Errorf("reading position: %w", err)
+	}
+
+	if d.version == 1 {
+		var unused float32
+		if err := d.read(&unused); err != nil {
+			return "", fmt.Errorf("reading padding: %w", err)
+		}
+	} else if err := d.read(&bias); err != nil {
+		return "", fmt.Errorf("reading bias: %w", err)
+	}
+
+	if err := d.read(&rot); err != nil {
+		return "", fmt.Errorf("reading rotation: %w", err)
+	}
+
+	if bias != 0 {
+		pos[0] += float32(((bias & placementBiasMask) - placementBiasOffset) << placementBiasShift)
+		pos[2] += float32((((bias >> placementBiasZShift) & placementBiasMask) - placementBiasOffset) << placementBiasShift)
+	}
+
+	return fmt.Sprintf(
+		"%s (%s; %s, %s, %s)",
+		formatFloats(pos[:]),
+		formatFloat(rot[0]), formatFloat(rot[1]), formatFloat(rot[2]), formatFloat(rot[3]),
+	), nil
+}
+
+// decodeToken converts the numeric representation of a token (a
+// base-38 encoded string) into its string form
+func decodeToken(v uint64) string {
+	var buf []byte
+	for v > 0 {
+		idx := v % uint64(len(tokenChars)+1)
+		if idx > 0 {
+			buf = append(buf, tokenChars[idx-1])
+		}
+		v /= uint64(len(tokenChars) + 1)
+	}
+
+	return string(buf)
+}
+
+// formatFloat renders floats the way the game does: integral values
+// are written as numbers, everything else as hex representation of
+// their binary value
+func formatFloat(v float32) string {
+	if float64(v) == math.Trunc(float64(v)) && math.Abs(float64(v)) < maxIntegralFloat {
+		return strconv.FormatInt(int64(v), 10)
+	}
+
+	return fmt.Sprintf("&%08x", math.Float32bits(v))
+}
+
+func formatFloats(v []float32) string {
+	parts := make([]string, len(v))
+	for i := range v {
+		parts[i] = formatFloat(v[i])
+	}
+
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// formatNameless renders a nameless ID as groups of 16 bit hex values
+// omitting leading empty groups
+func formatNameless(v uint64) string {
+	var parts []string
+	for shift := uint64Bits - namelessPartBits; shift >= 0; shift -= namelessPartBits {
+		part := (v >> shift) & namelessPartMask
+		if len(parts) == 0 && part == 0 && shift > 0 {
+			continue
+		}
+		parts = append(parts, strconv.FormatUint(part, 16))
+	}
+
+	return "_nameless." + strings.Join(parts, ".")
+}
+
+func formatString(s string) string {
+	var buf strings.Builder
+	buf.WriteByte('"')
+
+	for _, c := range []byte(s) {
+		switch {
+		case c == '"' || c == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+
+		case c == '\n':
+			buf.WriteString(`\n`)
+
+		case c == '\t':
+			buf.WriteString(`\t`)
+
+		case c < ' ':
+			fmt.Fprintf(&buf, `\x%02x`, c)
+
+		default:
+			buf.WriteByte(c)
+		}
+	}
+
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+func formatToken(s string) string {
+	if s == "" {
+		return `""`
+	}
+	return s
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Luzifer/rconfig/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// command is a subcommand of the CLI with its own options
+type command struct {
+	name        string
+	args        string
+	description string
+	// action describes the command in the error message if it fails
+	action string
+
+	minArgs int
+	maxArgs int // -1 for no limit
+
+	// options points to a struct embedding the option groups of the
+	// command which are copied into cfg after parsing
+	options any
+	run     func(args []string) error
+}
+
+var commands = []*command{
+	{
+		name: "cat", args: "<archive> <file> [file...]", description: "Print files to stdout",
+		action: "printing files", minArgs: 2, maxArgs: -1,
+		options: &struct {
+			ArchiveOptions
+			DecodeOptions
+			LogOptions
+		}{},
+		run: func(args []string) error { return catFiles(args[0], args[1:]) },
+	},
+	{
+		name: "diff", args: "<old archive> <new archive>", description: "Show files added, removed or modified between archives",
+		action: "comparing archives", minArgs: 2, maxArgs: 2,
+		options: &struct {
+			ArchiveOptions
+			DecodeOptions
+			DiffOptions
+			LogOptions
+		}{},
+		run: func(args []string) error { return diffArchives(args[0], args[1]) },
+	},
+	{
+		name: "extract", args: "<archive> [pattern...]", description: "Extract files into a directory or an archive",
+		action: "extracting files", minArgs: 1, maxArgs: -1,
+		options: &struct {
+			ArchiveOptions
+			DecodeOptions
+			ExtractOptions
+			LogOptions
+			SelectOptions
+		}{},
+		run: func(args []string) error { return extractArchive(args[0], args[1:]) },
+	},
+	{
+		name: "info", args: "<archive> [file...]", description: "Show details of the archive or of files within it",
+		action: "inspecting archive", minArgs: 1, maxArgs: -1,
+		options: &struct {
+			ArchiveOptions
+			LogOptions
+		}{},
+		run: func(args []string) error { return infoArchive(args[0], args[1:]) },
+	},
+	{
+		name: "list", args: "<archive> [pattern...]", description: "List files",
+		action: "listing files", minArgs: 1, maxArgs: -1,
+		options: &struct {
+			ArchiveOptions
+			ListOptions
+			LogOptions
+			SelectOptions
+		}{},
+		run: func(args []string) error { return listFiles(args[0], args[1:]) },
+	},
+	{
+		name: "pack", args: "<archive> <source directory>", description: "Create a HashFS v2 archive from a directory",
+		action: "packing archive", minArgs: 2, maxArgs: 2,
+		options: &struct {
+			LogOptions
+		}{},
+		run: func(args []string) error { return packArchive(args[0], args[1]) },
+	},
+	{
+		name: "search", args: "<archive> <regex> [pattern...]", description: "Search the content of files",
+		action: "searching files", minArgs: 2, maxArgs: -1,
+		options: &struct {
+			ArchiveOptions
+			DecodeOptions
+			LogOptions
+			SearchOptions
+			SelectOptions
+		}{},
+		run: func(args []string) error { return searchArchive(args[0], args[1], args[2:]) },
+	},
+	{
+		name: "verify", args: "<archive>", description: "Check the integrity of the archive",
+		action: "verifying archive", minArgs: 1, maxArgs: 1,
+		options: &struct {
+			ArchiveOptions
+			LogOptions
+		}{},
+		run: func(args []string) error { return verifyArchive(args[0]) },
+	},
+}
+
+// findCommand returns the command with the given name or nil
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// parseCommand parses the options of the command given as the first
+// argument into cfg and returns the positional arguments
+func parseCommand(cmd *command) ([]string, error) {
+	args := os.Args
+	defer func() { os.Args = args }()
+
+	// The flag set is named after the program, name it after the
+	// command instead to show its usage in the help
+	os.Args = append([]string{strings.Join([]string{args[0], cmd.name, cmd.args}, " ")}, args[2:]...)
+
+	if err := parseOptions(cmd.options); err != nil {
+		return nil, err
+	}
+
+	src := reflect.ValueOf(cmd.options).Elem()
+	dst := reflect.ValueOf(&cfg).Elem()
+	for i := range src.NumField() {
+		dst.FieldByName(src.Type().Field(i).Name).Set(src.Field(i))
+	}
+
+	return rconfig.Args()[1:], nil
+}
+
+// parseLegacy parses the options of all commands for invocations
+// without command in front: files are listed or, using --extract or
+// --to, extracted. Commands given after leading options still work.
+func parseLegacy() (*command, []string, error) {
+	if err := parseOptions(&cfg); err != nil {
+		return nil, nil, err
+	}
+
+	args := rconfig.Args()[1:]
+	if len(args) > 0 {
+		if cmd := findCommand(args[0]); cmd != nil {
+			return cmd, args[1:], nil
+		}
+	}
+
+	if len(args) == 0 && !cfg.VersionAndExit {
+		writeCommands(os.Stderr)
+		return nil, nil, errors.New("no SCS archive given")
+	}
+
+	if cfg.Extract || cfg.To != "" {
+		return findCommand("extract"), args, nil
+	}
+	return findCommand("list"), args, nil
+}
+
+// parseOptions parses the CLI options into the given struct. If help
+// is requested the usage of the options is printed and the program
+// exits successfully instead of failing like the flag set does.
+func parseOptions(options any) error {
+	if helpRequested(os.Args[1:]) {
+		// Parse without arguments to print the defaults of the options
+		os.Args = os.Args[:1]
+		if err := rconfig.Parse(options); err != nil {
+			return fmt.Errorf("parsing CLI options: %w", err)
+		}
+
+		rconfig.Usage()
+		os.Exit(0)
+	}
+
+	if err := rconfig.ParseAndValidate(options); err != nil {
+		return fmt.Errorf("parsing CLI options: %w", err)
+	}
+
+	return nil
+}
+
+// helpRequested reports whether -h or --help is given before the end
+// of the options
+func helpRequested(args []string) bool {
+	for _, arg := range args {
+		switch arg {
+		case "--":
+			return false
+		case "-h", "--help":
+			return true
+		}
+	}
+	return false
+}
+
+// showHelp prints the options of the given command or the list of
+// commands and exits
+func showHelp(args []string) {
+	if len(args) > 0 {
+		cmd := findCommand(args[0])
+		if cmd == nil {
+			logrus.Fatalf("unknown command %q", args[0])
+		}
+
+		// Parsing prints the usage of the command and exits
+		os.Args = []string{os.Args[0], cmd.name, "--help"}
+		parseCommand(cmd) //nolint:errcheck,gosec // Does not return
+	}
+
+	writeCommands(os.Stdout)
+	os.Exit(0)
+}
+
+// writeCommands writes the usage overview listing all commands
+func writeCommands(w io.Writer) {
+	fmt.Fprintf(w, "Usage: scs-extract <command> [options] <arguments>\n\nCommands:\n")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:mnd
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", cmd.name, cmd.args, cmd.description)
+	}
+	tw.Flush() //nolint:errcheck,gosec // Output to terminal
+
+	fmt.Fprintf(w, "\nWithout command files are listed (or extracted using --extract or --to)\n"+
+		"with the options of the list and extract commands.\n"+
+		"Use \"scs-extract help <command>\" to show the options of a command.\n")
+}
@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestHelpRequested(t *testing.T) {
+	for _, tc := range []struct {
+		args   []string
+		expect bool
+	}{
+		{[]string{"info", "--help"}, true},
+		{[]string{"list", "-h", "base.scs"}, true},
+		{[]string{"cat", "base.scs", "--", "--help"}, false},
+		{[]string{"search", "base.scs", "help"}, false},
+		{nil, false},
+	} {
+		if got := helpRequested(tc.args); got != tc.expect {
+			t.Errorf("%v: expected %v, got %v", tc.args, tc.expect, got)
+		}
+	}
+}
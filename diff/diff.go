@@ -0,0 +1,211 @@
+// Package diff compares the contents of two SCS archives (or two
+// merged game installations) and creates unified diffs of changed text
+// files
+package diff
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Luzifer/scs-extract/scs"
+)
+
+// Kinds of changes between the old and new set of files
+const (
+	KindAdded    Kind = "added"
+	KindRemoved  Kind = "removed"
+	KindModified Kind = "modified"
+)
+
+// DefaultTextExtensions lists the extensions of files to create
+// unified diffs for if Options.TextExtensions is not set
+var DefaultTextExtensions = []string{".sii", ".sui", ".mat"}
+
+type (
+	// Kind describes the type of a Change
+	Kind string
+
+	// Change describes a file which differs between the two sets
+	Change struct {
+		Kind Kind
+		// Name is the name of the file or its hash (formatted as
+		// "<hash>") if the name is not known
+		Name string
+
+		// OldHash and NewHash contain the SHA256 sum of the decompressed
+		// content. They are only set when the content was compared.
+		OldHash string
+		NewHash string
+
+		// Unified contains the unified diff of modified text files if
+		// requested through Options.Unified
+		Unified string
+	}
+
+	// Options controls how files are compared
+	Options struct {
+		// Unified enables creating unified diffs for modified text files
+		Unified bool
+		// TextExtensions lists the extensions of text files to create
+		// unified diffs for (defaults to DefaultTextExtensions)
+		TextExtensions []string
+		// Transform is applied to the content before creating the
+		// unified diff (i.e. to decode SII files) if set
+		Transform func(f *scs.File, r io.Reader) (io.Reader, error)
+	}
+)
+
+// Compare reports the added, removed and modified files between the
+// old and new set of files sorted by name. Files are matched by their
+// name (or their hash if the name is unknown) and compared by the
+// SHA256 sum of their decompressed content.
+func Compare(oldFiles, newFiles []*scs.File, opts Options) ([]Change, error) {
+	var (
+		changes  []Change
+		oldByKey = indexFiles(oldFiles)
+		newByKey = indexFiles(newFiles)
+	)
+
+	for key, o := range oldByKey {
+		n, ok := newByKey[key]
+		if !ok {
+			changes = append(changes, Change{Kind: KindRemoved, Name: key})
+			continue
+		}
+
+		c, err := compareFiles(key, o, n, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		if c != nil {
+			changes = append(changes, *c)
+		}
+	}
+
+	for key := range newByKey {
+		if _, ok := oldByKey[key]; !ok {
+			changes = append(changes, Change{Kind: KindAdded, Name: key})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes, nil
+}
+
+// compareFiles returns the Change between the two versions of a file
+// or nil if the content is equal
+func compareFiles(key string, o, n *scs.File, opts Options) (c *Change, err error) {
+	c = &Change{Kind: KindModified, Name: key}
+	if c.OldHash, err = contentHash(o); err != nil {
+		return nil, fmt.Errorf("hashing old %s: %w", key, err)
+	}
+
+	if c.NewHash, err = contentHash(n); err != nil {
+		return nil, fmt.Errorf("hashing new %s: %w", key, err)
+	}
+
+	if c.OldHash == c.NewHash {
+		return nil, nil //nolint:nilnil // Equal files are no change
+	}
+
+	if opts.Unified && isTextFile(key, opts.TextExtensions) {
+		if c.Unified, err = unifiedDiff(key, o, n, opts.Transform); err != nil {
+			return nil, fmt.Errorf("creating diff for %s: %w", key, err)
+		}
+	}
+
+	return c, nil
+}
+
+func contentHash(f *scs.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("opening file: %w", err)
+	}
+	defer rc.Close() //nolint:errcheck
+
+	h := sha256.New()
+	if _, err = io.Copy(h, rc); err != nil {
+		return "", fmt.Errorf("reading file: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// indexFiles maps the files by their name or their hash for files
+// without known name. Directories are skipped as their changes are
+// reflected in the files within them.
+func indexFiles(files []*scs.File) map[string]*scs.File {
+	idx := make(map[string]*scs.File, len(files))
+	for _, f := range files {
+		if f.IsDirectory {
+			continue
+		}
+
+		key := f.Name
+		if key == "" {
+			key = fmt.Sprintf("<%016x>", f.Hash)
+		}
+		idx[key] = f
+	}
+
+	return idx
+}
+
+func readContent(f *scs.File, transform func(*scs.File, io.Reader) (io.Reader, error)) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("opening file: %w", err)
+	}
+	defer rc.Close() //nolint:errcheck
+
+	var r io.Reader = rc
+	if transform != nil {
+		if r, err = transform(f, rc); err != nil {
+			return "", fmt.Errorf("transforming content: %w", err)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if _, err = io.Copy(buf, r); err != nil {
+		return "", fmt.Errorf("reading file: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+func isTextFile(name string, extensions []string) bool {
+	if extensions == nil {
+		extensions = DefaultTextExtensions
+	}
+
+	ext := path.Ext(name)
+	for _, e := range extensions {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func unifiedDiff(name string, o, n *scs.File, transform func(*scs.File, io.Reader) (io.Reader, error)) (string, error) {
+	oldText, err := readContent(o, transform)
+	if err != nil {
+		return "", fmt.Errorf("reading old file: %w", err)
+	}
+
+	newText, err := readContent(n, transform)
+	if err != nil {
+		return "", fmt.Errorf("reading new file: %w", err)
+	}
+
+	return Unified("a/"+name, "b/"+name, oldText, newText), nil
+}
@@ -0,0 +1,88 @@
+package diff
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Luzifer/scs-extract/scs"
+)
+
+func openTestTree(t *testing.T, files map[string]string) []*scs.File {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+			t.Fatalf("creating directory: %s", err)
+		}
+
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatalf("writing file: %s", err)
+		}
+	}
+
+	r, err := scs.NewDirReader(dir)
+	if err != nil {
+		t.Fatalf("reading directory: %s", err)
+	}
+
+	return r.Files
+}
+
+func TestCompare(t *testing.T) {
+	oldFiles := openTestTree(t, map[string]string{
+		"def/city.sii":    "SiiNunit\n{\ncity : city.berlin {\n population: 100\n}\n}\n",
+		"def/company.sii": "unchanged",
+		"def/removed.sii": "gone",
+		"model/truck.pmg": "binary v1",
+	})
+	newFiles := openTestTree(t, map[string]string{
+		"def/city.sii":    "SiiNunit\n{\ncity : city.berlin {\n population: 200\n}\n}\n",
+		"def/company.sii": "unchanged",
+		"def/added.sii":   "new",
+		"model/truck.pmg": "binary v2",
+	})
+
+	changes, err := Compare(oldFiles, newFiles, Options{Unified: true})
+	if err != nil {
+		t.Fatalf("comparing: %s", err)
+	}
+
+	expect := []struct {
+		kind    Kind
+		name    string
+		unified string
+	}{
+		{KindAdded, "def/added.sii", ""},
+		{KindModified, "def/city.sii", `--- a/def/city.sii
++++ b/def/city.sii
+@@ -1,6 +1,6 @@
+ SiiNunit
+ {
+ city : city.berlin {
+- population: 100
++ population: 200
+ }
+ }
+`},
+		{KindRemoved, "def/removed.sii", ""},
+		{KindModified, "model/truck.pmg", ""},
+	}
+
+	if len(changes) != len(expect) {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+
+	for i, e := range expect {
+		c := changes[i]
+		if c.Kind != e.kind || c.Name != e.name || c.Unified != e.unified {
+			t.Errorf("unexpected change %d: %+v", i, c)
+		}
+	}
+
+	if changes[3].OldHash == "" || changes[3].OldHash == changes[3].NewHash {
+		t.Errorf("expected differing content hashes: %+v", changes[3])
+	}
+}
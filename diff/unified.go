@@ -0,0 +1,275 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around changes
+const contextLines = 3
+
+type (
+	editKind byte
+
+	edit struct {
+		kind editKind
+		// oldLine and newLine are the zero-based line numbers in the old
+		// and new text the edit is positioned at
+		oldLine, newLine int
+		text             string
+	}
+)
+
+const (
+	editEqual  editKind = ' '
+	editDelete editKind = '-'
+	editInsert editKind = '+'
+)
+
+// Unified returns the unified diff between the old and new text or an
+// empty string if both are equal
+func Unified(oldName, newName, oldText, newText string) string {
+	edits := diffLines(splitLines(oldText), splitLines(newText))
+
+	buf := new(strings.Builder)
+	for start := 0; start < len(edits); {
+		// Find the next change
+		for start < len(edits) && edits[start].kind == editEqual {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+
+		// Extend the hunk until there are more unchanged lines than
+		// shown as context around two changes
+		end, equal := start, 0
+		for i := start; i < len(edits) && equal <= 2*contextLines; i++ {
+			if edits[i].kind == editEqual {
+				equal++
+				continue
+			}
+			end, equal = i+1, 0
+		}
+
+		from := max(0, start-contextLines)
+		to := min(len(edits), end+contextLines)
+
+		if buf.Len() == 0 {
+			fmt.Fprintf(buf, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		writeHunk(buf, edits[from:to])
+
+		start = to
+	}
+
+	return buf.String()
+}
+
+// diffLines calculates the shortest edit script to transform a into b
+// using the linear space variant of the Myers algorithm: instead of
+// remembering every step for backtracking, the middle snake of the
+// shortest path is searched from both ends and both halves are solved
+// recursively
+func diffLines(a, b []string) []edit {
+	size := (len(a)+len(b)+1)/2 + 1 //nolint:mnd
+	d := &differ{
+		a:  a,
+		b:  b,
+		vf: make([]int, 2*size+1),
+		vb: make([]int, 2*size+1),
+	}
+	d.compare(0, len(a), 0, len(b))
+
+	return groupChanges(d.edits)
+}
+
+type differ struct {
+	a, b   []string
+	vf, vb []int
+	edits  []edit
+}
+
+// compare adds the edits transforming a[aLo:aHi] into b[bLo:bHi]
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.edits = append(d.edits, edit{kind: editEqual, oldLine: aLo, newLine: bLo, text: d.a[aLo]})
+		aLo++
+		bLo++
+	}
+
+	// The common suffix is added after the changes in between
+	aEnd := aHi
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		aHi--
+		bHi--
+	}
+
+	switch {
+	case aLo == aHi:
+		for y := bLo; y < bHi; y++ {
+			d.edits = append(d.edits, edit{kind: editInsert, oldLine: aLo, newLine: y, text: d.b[y]})
+		}
+
+	case bLo == bHi:
+		for x := aLo; x < aHi; x++ {
+			d.edits = append(d.edits, edit{kind: editDelete, oldLine: x, newLine: bLo, text: d.a[x]})
+		}
+
+	default:
+		// With common prefix and suffix removed and both sides not
+		// empty at least two edits are required, so both halves are
+		// smaller than the whole
+		x, y, u, v := d.middleSnake(aLo, aHi, bLo, bHi)
+		d.compare(aLo, x, bLo, y)
+		for ; x < u; x, y = x+1, y+1 {
+			d.edits = append(d.edits, edit{kind: editEqual, oldLine: x, newLine: y, text: d.a[x]})
+		}
+		d.compare(u, aHi, v, bHi)
+	}
+
+	for ; aHi < aEnd; aHi, bHi = aHi+1, bHi+1 {
+		d.edits = append(d.edits, edit{kind: editEqual, oldLine: aHi, newLine: bHi, text: d.a[aHi]})
+	}
+}
+
+// middleSnake returns start (x, y) and end (u, v) of the diagonal in
+// the middle of the shortest edit path from (aLo, bLo) to (aHi, bHi).
+// The forward search extends paths from the start, the backward search
+// from the end (with coordinates counted from the end) until they meet.
+//
+//nolint:gocyclo // Algorithm is easier to follow in one piece
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int) {
+	var (
+		n, m   = aHi - aLo, bHi - bLo
+		delta  = n - m
+		odd    = delta%2 != 0
+		maxD   = (n + m + 1) / 2 //nolint:mnd
+		offset = maxD + 1
+	)
+
+	d.vf[offset+1], d.vb[offset+1] = 0, 0
+
+	for step := 0; step <= maxD; step++ {
+		for k := -step; k <= step; k += 2 {
+			xs := d.vf[offset+k-1] + 1
+			if k == -step || (k != step && d.vf[offset+k-1] < d.vf[offset+k+1]) {
+				xs = d.vf[offset+k+1]
+			}
+
+			xe, ye := xs, xs-k
+			for xe < n && ye < m && d.a[aLo+xe] == d.b[bLo+ye] {
+				xe++
+				ye++
+			}
+			d.vf[offset+k] = xe
+
+			// Backward paths of the previous step run on diagonal delta-k
+			if c := delta - k; odd && c >= -(step-1) && c <= step-1 && xe+d.vb[offset+c] >= n {
+				return aLo + xs, bLo + xs - k, aLo + xe, bLo + ye
+			}
+		}
+
+		for c := -step; c <= step; c += 2 {
+			xs := d.vb[offset+c-1] + 1
+			if c == -step || (c != step && d.vb[offset+c-1] < d.vb[offset+c+1]) {
+				xs = d.vb[offset+c+1]
+			}
+
+			xe, ye := xs, xs-c
+			for xe < n && ye < m && d.a[aHi-1-xe] == d.b[bHi-1-ye] {
+				xe++
+				ye++
+			}
+			d.vb[offset+c] = xe
+
+			if k := delta - c; !odd && k >= -step && k <= step && xe+d.vf[offset+k] >= n {
+				return aHi - xe, bHi - ye, aHi - xs, bHi - xs + c
+			}
+		}
+	}
+
+	// Unreachable: the paths meet after at most maxD steps
+	return aLo, bLo, aLo, bLo
+}
+
+// groupChanges reorders every run of changes to list all deletions
+// before the insertions as diff tools do
+func groupChanges(edits []edit) []edit {
+	out := make([]edit, 0, len(edits))
+
+	for i := 0; i < len(edits); {
+		if edits[i].kind == editEqual {
+			out = append(out, edits[i])
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(edits) && edits[end].kind != editEqual {
+			end++
+		}
+
+		// Deletions are positioned at the start of the run in the new
+		// text, insertions after all deletions in the old text
+		var (
+			newStart = edits[i].newLine
+			oldEnd   = edits[i].oldLine
+			inserts  []edit
+		)
+		for _, e := range edits[i:end] {
+			if e.kind == editDelete {
+				oldEnd = e.oldLine + 1
+			}
+		}
+
+		for _, e := range edits[i:end] {
+			if e.kind == editDelete {
+				e.newLine = newStart
+				out = append(out, e)
+				continue
+			}
+			e.oldLine = oldEnd
+			inserts = append(inserts, e)
+		}
+		out = append(out, inserts...)
+
+		i = end
+	}
+
+	return out
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+func writeHunk(buf *strings.Builder, edits []edit) {
+	var oldCount, newCount int
+	for _, e := range edits {
+		if e.kind != editInsert {
+			oldCount++
+		}
+		if e.kind != editDelete {
+			newCount++
+		}
+	}
+
+	oldStart, newStart := edits[0].oldLine+1, edits[0].newLine+1
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+
+	fmt.Fprintf(buf, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+	for _, e := range edits {
+		buf.WriteByte(byte(e.kind))
+		buf.WriteString(e.text)
+		buf.WriteByte('\n')
+	}
+}
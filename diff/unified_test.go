@@ -0,0 +1,81 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	var oldLines, newLines []string
+	for i := 1; i <= 20; i++ {
+		oldLines = append(oldLines, fmt.Sprintf("line %d", i))
+		switch i {
+		case 2:
+			newLines = append(newLines, "line 2 changed")
+		case 15:
+			// removed
+		default:
+			newLines = append(newLines, fmt.Sprintf("line %d", i))
+		}
+	}
+	newLines = append(newLines, "line 21")
+
+	got := Unified("a/test", "b/test", strings.Join(oldLines, "\n")+"\n", strings.Join(newLines, "\n")+"\n")
+	expect := `--- a/test
++++ b/test
+@@ -1,5 +1,5 @@
+ line 1
+-line 2
++line 2 changed
+ line 3
+ line 4
+ line 5
+@@ -12,9 +12,9 @@
+ line 12
+ line 13
+ line 14
+-line 15
+ line 16
+ line 17
+ line 18
+ line 19
+ line 20
++line 21
+`
+
+	if got != expect {
+		t.Errorf("unexpected diff:\n%s", got)
+	}
+}
+
+func TestUnifiedEqual(t *testing.T) {
+	if got := Unified("a", "b", "same\n", "same\n"); got != "" {
+		t.Errorf("expected empty diff, got:\n%s", got)
+	}
+
+	if got := Unified("a", "b", "", "new\n"); got != "--- a\n+++ b\n@@ -0,0 +1,1 @@\n+new\n" {
+		t.Errorf("unexpected diff for new file:\n%s", got)
+	}
+}
+
+func TestUnifiedRewrittenFile(t *testing.T) {
+	// Every line changed: the edit distance equals the number of lines
+	// which must not require memory growing with its square
+	var oldLines, newLines []string
+	for i := 0; i < 5000; i++ {
+		oldLines = append(oldLines, fmt.Sprintf("old %d", i))
+		newLines = append(newLines, fmt.Sprintf("new %d", i))
+	}
+
+	got := Unified("a", "b", strings.Join(oldLines, "\n")+"\n", strings.Join(newLines, "\n")+"\n")
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+
+	if len(lines) != 3+10000 || lines[2] != "@@ -1,5000 +1,5000 @@" {
+		t.Fatalf("unexpected diff with %d lines starting %q", len(lines), lines[:3])
+	}
+
+	if lines[3] != "-old 0" || lines[5003] != "+new 0" {
+		t.Errorf("deletions are not grouped before insertions: %q, %q", lines[3], lines[5003])
+	}
+}
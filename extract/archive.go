@@ -0,0 +1,240 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Luzifer/scs-extract/scs"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Formats of archives written by ExtractArchive
+const (
+	FormatTar ArchiveFormat = iota
+	FormatTarGzip
+	FormatTarZstd
+	FormatZip
+)
+
+const archiveFilePermissions = 0o644
+
+type (
+	// ArchiveFormat is the format of the archive written by
+	// ExtractArchive
+	ArchiveFormat int
+
+	archiveFormatInfo struct {
+		name       string
+		extensions []string
+	}
+
+	// archiveWriter abstracts the tar and zip writers
+	archiveWriter interface {
+		add(name string, size int64, content io.Reader) error
+		Close() error
+	}
+
+	tarArchiveWriter struct {
+		compressor io.WriteCloser
+		modTime    time.Time
+		w          *tar.Writer
+	}
+
+	zipArchiveWriter struct {
+		modTime time.Time
+		w       *zip.Writer
+	}
+)
+
+var archiveFormats = map[ArchiveFormat]archiveFormatInfo{
+	FormatTar:     {"tar", []string{".tar"}},
+	FormatTarGzip: {"tar.gz", []string{".tar.gz", ".tgz"}},
+	FormatTarZstd: {"tar.zst", []string{".tar.zst", ".tzst"}},
+	FormatZip:     {"zip", []string{".zip"}},
+}
+
+// ArchiveFormatFromName detects the format from the extension of the
+// given file name
+func ArchiveFormatFromName(name string) (ArchiveFormat, error) {
+	name = strings.ToLower(name)
+	for f, info := range archiveFormats {
+		for _, ext := range info.extensions {
+			if strings.HasSuffix(name, ext) {
+				return f, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no archive format known for %q", name)
+}
+
+// ParseArchiveFormat parses the name of the format (tar, tar.gz,
+// tar.zst, zip)
+func ParseArchiveFormat(name string) (ArchiveFormat, error) {
+	for f, info := range archiveFormats {
+		if info.name == name {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown archive format %q", name)
+}
+
+func (f ArchiveFormat) String() string {
+	if info, ok := archiveFormats[f]; ok {
+		return info.name
+	}
+	return fmt.Sprintf("ArchiveFormat(%d)", int(f))
+}
+
+// ExtractArchive writes the given files into an archive of the given
+// format instead of the Dest directory. Directories are skipped, names
+// are checked and cleaned like for Extract. Files are written in the
+// given order, Workers, Overwrite and Manifest are not used.
+func (e Extractor) ExtractArchive(ctx context.Context, w io.Writer, format ArchiveFormat, files []*scs.File) (err error) {
+	aw, err := newArchiveWriter(w, format)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := aw.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("closing archive: %w", cerr)
+		}
+	}()
+
+	for _, f := range files {
+		if err = ctx.Err(); err != nil {
+			return fmt.Errorf("extracting files: %w", err)
+		}
+
+		if f.IsDirectory {
+			continue
+		}
+
+		name, err := SafeName(f.Name)
+		if err != nil {
+			if e.OnRejected == nil {
+				return fmt.Errorf("extracting %s: %w", f.Name, err)
+			}
+			e.OnRejected(f, err)
+			continue
+		}
+
+		if err = e.addToArchive(ctx, aw, name, f); err != nil {
+			return fmt.Errorf("extracting %s: %w", f.Name, err)
+		}
+
+		if e.OnExtracted != nil {
+			e.OnExtracted(f)
+		}
+	}
+
+	return nil
+}
+
+func (e Extractor) addToArchive(ctx context.Context, aw archiveWriter, name string, f *scs.File) error {
+	src, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("opening file from archive: %w", err)
+	}
+	defer src.Close() //nolint:errcheck
+
+	var (
+		content io.Reader = ctxReader{ctx: ctx, r: src}
+		size              = int64(f.Size)
+	)
+
+	if e.Transform != nil {
+		if content, err = e.Transform(f, content); err != nil {
+			return fmt.Errorf("transforming content: %w", err)
+		}
+
+		// The size of the transformed content is unknown and tar
+		// headers need it in advance
+		data, err := io.ReadAll(content)
+		if err != nil {
+			return fmt.Errorf("reading file contents: %w", err)
+		}
+		content, size = bytes.NewReader(data), int64(len(data))
+	}
+
+	return aw.add(name, size, content)
+}
+
+func newArchiveWriter(w io.Writer, format ArchiveFormat) (archiveWriter, error) {
+	// All entries share the time the archive was created at
+	modTime := time.Now()
+
+	switch format {
+	case FormatTar:
+		return &tarArchiveWriter{modTime: modTime, w: tar.NewWriter(w)}, nil
+
+	case FormatTarGzip:
+		gw := gzip.NewWriter(w)
+		return &tarArchiveWriter{compressor: gw, modTime: modTime, w: tar.NewWriter(gw)}, nil
+
+	case FormatTarZstd:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, fmt.Errorf("creating zstd writer: %w", err)
+		}
+		return &tarArchiveWriter{compressor: zw, modTime: modTime, w: tar.NewWriter(zw)}, nil
+
+	case FormatZip:
+		return &zipArchiveWriter{modTime: modTime, w: zip.NewWriter(w)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported archive format %s", format)
+}
+
+func (t *tarArchiveWriter) add(name string, size int64, content io.Reader) error {
+	if err := t.w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     archiveFilePermissions,
+		ModTime:  t.modTime,
+		Format:   tar.FormatPAX,
+	}); err != nil {
+		return fmt.Errorf("writing tar header: %w", err)
+	}
+
+	if _, err := io.Copy(t.w, content); err != nil {
+		return fmt.Errorf("writing file contents: %w", err)
+	}
+
+	return nil
+}
+
+func (t *tarArchiveWriter) Close() error {
+	err := t.w.Close()
+	if t.compressor != nil {
+		err = errors.Join(err, t.compressor.Close())
+	}
+	return err //nolint:wrapcheck
+}
+
+func (z *zipArchiveWriter) add(name string, _ int64, content io.Reader) error {
+	w, err := z.w.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: z.modTime,
+	})
+	if err != nil {
+		return fmt.Errorf("writing zip header: %w", err)
+	}
+
+	if _, err = io.Copy(w, content); err != nil {
+		return fmt.Errorf("writing file contents: %w", err)
+	}
+
+	return nil
+}
+
+func (z *zipArchiveWriter) Close() error { return z.w.Close() } //nolint:wrapcheck
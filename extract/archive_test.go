@@ -0,0 +1,123 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Luzifer/scs-extract/scs"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestExtractArchive(t *testing.T) {
+	r := openTestArchive(t, map[string]string{
+		"def/city.sii":    "city",
+		"def/company.sii": "company",
+	})
+
+	files := append(r.Files, &scs.File{Name: "../escape.sii"}) //nolint:gocritic // Test data
+
+	for _, format := range []ArchiveFormat{FormatTar, FormatTarGzip, FormatTarZstd, FormatZip} {
+		var (
+			buf      bytes.Buffer
+			prefix   string
+			rejected int
+		)
+
+		ex := Extractor{OnRejected: func(*scs.File, error) { rejected++ }}
+		if format != FormatTar {
+			// Transformed content has to be buffered for tar headers
+			prefix = "# "
+			ex.Transform = func(_ *scs.File, r io.Reader) (io.Reader, error) {
+				return io.MultiReader(strings.NewReader(prefix), r), nil
+			}
+		}
+
+		if err := ex.ExtractArchive(context.Background(), &buf, format, files); err != nil {
+			t.Fatalf("%s: writing archive: %s", format, err)
+		}
+
+		got := readTestArchive(t, format, buf.Bytes())
+		if len(got) != 2 || got["def/city.sii"] != prefix+"city" || got["def/company.sii"] != prefix+"company" {
+			t.Errorf("%s: unexpected archive content: %v", format, got)
+		}
+
+		if rejected != 1 {
+			t.Errorf("%s: expected unsafe file to be rejected", format)
+		}
+	}
+
+	if f, err := ArchiveFormatFromName("out/def.TAR.ZST"); err != nil || f != FormatTarZstd {
+		t.Errorf("unexpected format from name: %s, %v", f, err)
+	}
+}
+
+func readTestArchive(t *testing.T, format ArchiveFormat, data []byte) map[string]string {
+	t.Helper()
+
+	files := make(map[string]string)
+
+	if format == FormatZip {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("reading zip: %s", err)
+		}
+
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatalf("opening %s: %s", f.Name, err)
+			}
+			content, err := io.ReadAll(rc)
+			rc.Close() //nolint:errcheck,gosec
+			if err != nil {
+				t.Fatalf("reading %s: %s", f.Name, err)
+			}
+			files[f.Name] = string(content)
+		}
+
+		return files
+	}
+
+	var r io.Reader = bytes.NewReader(data)
+	switch format {
+	case FormatTarGzip:
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatalf("opening gzip: %s", err)
+		}
+		r = gr
+
+	case FormatTarZstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			t.Fatalf("opening zstd: %s", err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading tar: %s", err)
+		}
+
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("reading %s: %s", hdr.Name, err)
+		}
+		files[hdr.Name] = string(content)
+	}
+
+	return files
+}
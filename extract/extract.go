@@ -0,0 +1,226 @@
+// Package extract contains a concurrent engine to write the files of
+// SCS archives to the local filesystem
+package extract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/Luzifer/scs-extract/scs"
+	"golang.org/x/sync/errgroup"
+)
+
+const dirPermissions = 0o750
+
+type (
+	// Extractor writes files to the Dest directory using multiple
+	// workers. As File.Open reads through an io.ReaderAt the files of
+	// one archive can safely be extracted in parallel.
+	Extractor struct {
+		// Dest is the directory to extract the files into
+		Dest string
+
+		// Workers is the number of files extracted concurrently. If not
+		// set runtime.NumCPU workers are used.
+		Workers int
+
+		// Transform is applied to the content of every file before it
+		// is written (i.e. to decode SII files) if set
+		Transform func(f *scs.File, r io.Reader) (io.Reader, error)
+
+		// OnExtracted is called after a file was extracted. Calls are
+		// made in the order the files were passed to Extract and never
+		// concurrently.
+		OnExtracted func(f *scs.File)
+
+		// Overwrite controls how files already existing in Dest are
+		// handled, by default they are overwritten
+		Overwrite OverwritePolicy
+
+		// Manifest records the written files and the archive entries
+		// they were extracted from if set. Using the manifest of a
+		// previous run entries extracted before are skipped without
+		// reading them as long as the files were not modified since.
+		Manifest *Manifest
+
+		// OnSkipped is called for files not written as they already
+		// exist or are unchanged according to the Overwrite policy.
+		// Calls are made like those to OnExtracted.
+		OnSkipped func(f *scs.File)
+
+		// OnRejected is called with the reason for every file not
+		// extracted as its name is unsafe (see SafeName) or its path
+		// within Dest contains a symlink. Calls are made like those to
+		// OnExtracted. If not set rejected files fail the extraction.
+		OnRejected func(f *scs.File, err error)
+	}
+
+	// result is reported by the workers for every file
+	result struct {
+		extracted bool
+		skipped   bool
+		rejected  error
+	}
+
+	// readCloser closes the archive file below a transformed reader
+	readCloser struct {
+		io.Reader
+		io.Closer
+	}
+
+	ctxReader struct {
+		ctx context.Context //nolint:containedctx // Required to abort long running copies
+		r   io.Reader
+	}
+)
+
+// Extract writes the given files to the Dest directory. Directories
+// are skipped as they are created when extracting the files inside
+// them. Files are never written outside Dest, unsafe files are
+// rejected. On the first error all remaining work is cancelled and
+// the error is returned.
+func (e Extractor) Extract(ctx context.Context, files []*scs.File) error {
+	workers := e.Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	if err := os.MkdirAll(e.Dest, dirPermissions); err != nil {
+		return fmt.Errorf("creating destination: %w", err)
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(workers)
+
+	// Every worker reports whether it extracted, skipped or rejected
+	// its file
+	done := make([]chan result, len(files))
+	for i := range done {
+		done[i] = make(chan result, 1)
+	}
+
+	// Report extracted files in order while the workers might finish
+	// them in any order
+	var (
+		reported = make(chan struct{})
+		stop     = make(chan struct{})
+	)
+	go func() {
+		defer close(reported)
+		for i := range files {
+			select {
+			case res := <-done[i]:
+				switch {
+				case res.rejected != nil:
+					e.OnRejected(files[i], res.rejected)
+				case res.extracted && e.OnExtracted != nil:
+					e.OnExtracted(files[i])
+				case res.skipped && e.OnSkipped != nil:
+					e.OnSkipped(files[i])
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	for i, f := range files {
+		if gctx.Err() != nil {
+			break
+		}
+
+		g.Go(func() error {
+			if err := gctx.Err(); err != nil {
+				// Another worker failed while this one was waiting for a slot
+				return err //nolint:wrapcheck
+			}
+
+			if f.IsDirectory {
+				// Don't care about directories, if they contain files they will be created
+				done[i] <- result{}
+				return nil
+			}
+
+			written, err := e.extractFile(gctx, f)
+			switch {
+			case errors.Is(err, ErrUnsafePath) && e.OnRejected != nil:
+				done[i] <- result{rejected: err}
+				return nil
+
+			case err != nil:
+				return fmt.Errorf("extracting %s: %w", f.Name, err)
+			}
+
+			done[i] <- result{extracted: written, skipped: !written}
+			return nil
+		})
+	}
+
+	err := g.Wait()
+	if err == nil {
+		// Cancelled from outside before all files were started
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		// Failed files will never report, the reporter has to give up
+		close(stop)
+	}
+	<-reported
+
+	if err != nil {
+		return fmt.Errorf("extracting files: %w", err)
+	}
+
+	return nil
+}
+
+// extractFile writes the file to the destination and reports whether
+// it was written or skipped due to the overwrite policy
+func (e Extractor) extractFile(ctx context.Context, f *scs.File) (bool, error) {
+	destPath, err := safeDestPath(e.Dest, f.Name)
+	if err != nil {
+		return false, err
+	}
+
+	if e.Overwrite == SkipExisting && exists(destPath) {
+		return false, nil
+	}
+
+	src := newManifestSource(f, e.Transform != nil)
+	if e.unchangedSource(destPath, src) {
+		return false, nil
+	}
+
+	open := func() (io.ReadCloser, error) {
+		src, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("opening file from archive: %w", err)
+		}
+
+		var content io.Reader = ctxReader{ctx: ctx, r: src}
+		if e.Transform != nil {
+			if content, err = e.Transform(f, content); err != nil {
+				src.Close() //nolint:errcheck,gosec // Already in error state
+				return nil, fmt.Errorf("transforming content: %w", err)
+			}
+		}
+
+		return readCloser{Reader: content, Closer: src}, nil
+	}
+
+	return e.writeFile(destPath, src, open)
+}
+
+// Read aborts the copy as soon as the context is cancelled
+func (c ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, fmt.Errorf("reading aborted: %w", err)
+	}
+
+	return c.r.Read(p) //nolint:wrapcheck
+}
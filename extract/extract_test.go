@@ -0,0 +1,127 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Luzifer/scs-extract/scs"
+)
+
+func openTestArchive(t *testing.T, files map[string]string) *scs.Reader {
+	t.Helper()
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.scs"))
+	if err != nil {
+		t.Fatalf("creating archive: %s", err)
+	}
+	t.Cleanup(func() { f.Close() }) //nolint:errcheck,gosec
+
+	w, err := scs.NewWriter(f)
+	if err != nil {
+		t.Fatalf("creating writer: %s", err)
+	}
+
+	for name, content := range files {
+		if err = w.AddFile(name, strings.NewReader(content)); err != nil {
+			t.Fatalf("adding %s: %s", name, err)
+		}
+	}
+
+	if err = w.Close(); err != nil {
+		t.Fatalf("closing writer: %s", err)
+	}
+
+	r, err := scs.NewReader(f)
+	if err != nil {
+		t.Fatalf("reading archive: %s", err)
+	}
+
+	return r
+}
+
+func TestExtract(t *testing.T) {
+	files := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		files[fmt.Sprintf("def/%02d/file.sii", i)] = strings.Repeat(fmt.Sprintf("content %d\n", i), i)
+	}
+
+	r := openTestArchive(t, files)
+	dest := t.TempDir()
+
+	var reported []string
+	err := Extractor{
+		Dest:    dest,
+		Workers: 8,
+		Transform: func(_ *scs.File, r io.Reader) (io.Reader, error) {
+			return io.MultiReader(strings.NewReader("# "), r), nil
+		},
+		OnExtracted: func(f *scs.File) { reported = append(reported, f.Name) },
+	}.Extract(context.Background(), r.Files)
+	if err != nil {
+		t.Fatalf("extracting: %s", err)
+	}
+
+	var expectReported []string
+	for _, f := range r.Files {
+		if !f.IsDirectory {
+			expectReported = append(expectReported, f.Name)
+		}
+	}
+
+	if strings.Join(reported, ",") != strings.Join(expectReported, ",") {
+		t.Errorf("files were not reported in order: %v", reported)
+	}
+
+	for name, expect := range files {
+		data, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name))) //#nosec:G304 // Test file
+		if err != nil {
+			t.Fatalf("reading extracted %s: %s", name, err)
+		}
+
+		if string(data) != "# "+expect {
+			t.Errorf("unexpected content for %s", name)
+		}
+	}
+}
+
+func TestExtractCancelsOnError(t *testing.T) {
+	r := openTestArchive(t, map[string]string{
+		"a.sii":     "a",
+		"def/b.sii": "b",
+	})
+
+	var calls int
+	err := Extractor{
+		Dest:    t.TempDir(),
+		Workers: 1,
+		Transform: func(f *scs.File, r io.Reader) (io.Reader, error) {
+			calls++
+			return nil, fmt.Errorf("broken")
+		},
+	}.Extract(context.Background(), r.Files)
+
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("expected transform error, got %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("expected extraction to stop after first error, got %d calls", calls)
+	}
+}
+
+func TestExtractCancelledContext(t *testing.T) {
+	r := openTestArchive(t, map[string]string{"a.sii": "a"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := (Extractor{Dest: t.TempDir()}).Extract(ctx, r.Files); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
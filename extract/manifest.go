@@ -0,0 +1,152 @@
+package extract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Luzifer/scs-extract/scs"
+)
+
+// ManifestName is the name of the manifest file written into the
+// destination directory
+const ManifestName = ".scs-extract-manifest.json"
+
+const manifestPermissions = 0o640
+
+type (
+	// Manifest records the files written by the Extractor to detect
+	// unchanged files on re-extraction without reading them again
+	Manifest struct {
+		Files map[string]ManifestEntry `json:"files"`
+
+		lock sync.Mutex
+	}
+
+	// ManifestEntry describes a file written to the destination. The
+	// hash is only trusted as long as size and modification time of
+	// the file on disk still match.
+	ManifestEntry struct {
+		Size    int64           `json:"size"`
+		SHA256  string          `json:"sha256"`
+		ModTime time.Time       `json:"mod_time"`
+		Source  *ManifestSource `json:"source,omitempty"`
+	}
+
+	// ManifestSource identifies the archive entry a file was extracted
+	// from. As long as the entry did not change it is skipped without
+	// reading it. Entries of ZIP archives and directories have no
+	// offset and are always compared by their content.
+	ManifestSource struct {
+		Hash           string `json:"hash"`
+		Size           uint32 `json:"size"`
+		CompressedSize uint32 `json:"compressed_size"`
+		Offset         uint64 `json:"offset"`
+		Transformed    bool   `json:"transformed,omitempty"`
+	}
+)
+
+// newManifestSource returns the identity of the archive entry or nil
+// if the entry cannot be identified without reading it
+func newManifestSource(f *scs.File, transformed bool) *ManifestSource {
+	if f.Offset() == 0 {
+		return nil
+	}
+
+	return &ManifestSource{
+		Hash:           fmt.Sprintf("%016x", f.Hash),
+		Size:           f.Size,
+		CompressedSize: f.CompressedSize,
+		Offset:         f.Offset(),
+		Transformed:    transformed,
+	}
+}
+
+// LoadManifest reads the manifest from the given path. If the file
+// does not exist an empty manifest is returned.
+func LoadManifest(name string) (*Manifest, error) {
+	m := &Manifest{Files: make(map[string]ManifestEntry)}
+
+	data, err := os.ReadFile(name) //#nosec:G304 // Intended to read arbitrary files
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return m, nil
+	case err != nil:
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+
+	if err = json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("decoding manifest: %w", err)
+	}
+
+	if m.Files == nil {
+		m.Files = make(map[string]ManifestEntry)
+	}
+
+	return m, nil
+}
+
+// Save writes the manifest to the given path
+func (m *Manifest) Save(name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding manifest: %w", err)
+	}
+
+	if err = os.WriteFile(name, data, manifestPermissions); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Manifest) lookup(name string) (ManifestEntry, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	entry, ok := m.Files[name]
+	return entry, ok
+}
+
+// record stores the file on disk with its already known hash and the
+// archive entry it was extracted from
+func (m *Manifest) record(name, path, sum string, src *ManifestSource) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("getting file info: %w", err)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.Files == nil {
+		m.Files = make(map[string]ManifestEntry)
+	}
+	m.Files[name] = ManifestEntry{Size: info.Size(), SHA256: sum, ModTime: info.ModTime(), Source: src}
+	return nil
+}
+
+// hashFile calculates the SHA256 sum of the file at the given path
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path) //#nosec:G304 // Intended to read files within destination
+	if err != nil {
+		return "", fmt.Errorf("opening file: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("reading file: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,285 @@
+package extract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Policies for files already existing in the destination
+const (
+	// Overwrite replaces existing files
+	Overwrite OverwritePolicy = iota
+	// SkipExisting keeps existing files without reading the archive
+	SkipExisting
+	// OverwriteIfDifferent replaces existing files only if their size
+	// or content differs
+	OverwriteIfDifferent
+	// KeepBoth writes files differing from the existing ones next to
+	// them using a numbered suffix (i.e. "city.1.sii")
+	KeepBoth
+)
+
+const (
+	// maxSuffix limits the numbered suffixes tried by KeepBoth
+	maxSuffix = 1000
+
+	filePermissions = 0o644
+)
+
+// OverwritePolicy controls how the Extractor handles files already
+// existing in the destination
+type OverwritePolicy int
+
+var overwritePolicyNames = map[OverwritePolicy]string{
+	Overwrite:            "overwrite",
+	SkipExisting:         "skip",
+	OverwriteIfDifferent: "if-different",
+	KeepBoth:             "keep-both",
+}
+
+// ParseOverwritePolicy parses the name of the policy (overwrite, skip,
+// if-different, keep-both)
+func ParseOverwritePolicy(name string) (OverwritePolicy, error) {
+	for p, n := range overwritePolicyNames {
+		if n == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown overwrite policy %q", name)
+}
+
+func (p OverwritePolicy) String() string {
+	if name, ok := overwritePolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("OverwritePolicy(%d)", int(p))
+}
+
+// writeFile writes the content returned by open to destPath according
+// to the overwrite policy and reports whether the file was written
+//
+//nolint:gocyclo // Sequential checks, fine to understand
+func (e Extractor) writeFile(destPath string, src *ManifestSource, open func() (io.ReadCloser, error)) (bool, error) {
+	if e.Overwrite == Overwrite || !exists(destPath) {
+		// Nothing to compare with, write directly
+		return true, e.copyFile(destPath, src, open)
+	}
+
+	// Hash the content first to compare it with the existing file, it
+	// is only read a second time if it needs to be written
+	size, sum, err := hashContent(open)
+	if err != nil {
+		return false, err
+	}
+
+	// Compare with the existing file and for KeepBoth also with the
+	// copies written by previous runs
+	target := destPath
+	for i := 1; ; i++ {
+		same, err := e.sameContent(target, size, sum, src)
+		if err != nil {
+			return false, err
+		}
+
+		if same {
+			return false, nil
+		}
+
+		if e.Overwrite != KeepBoth {
+			break
+		}
+
+		if i > maxSuffix {
+			return false, fmt.Errorf("no free name found for %s", destPath)
+		}
+
+		if target = suffixedName(destPath, i); !exists(target) {
+			break
+		}
+	}
+
+	return true, e.copyFile(target, src, open)
+}
+
+// copyFile opens the content and writes it into destPath
+func (e Extractor) copyFile(destPath string, src *ManifestSource, open func() (io.ReadCloser, error)) error {
+	content, err := open()
+	if err != nil {
+		return err
+	}
+	defer content.Close() //nolint:errcheck
+
+	return e.createFile(destPath, src, content)
+}
+
+// hashContent reads the content returned by open and returns its size
+// and SHA256 hash
+func hashContent(open func() (io.ReadCloser, error)) (int64, string, error) {
+	content, err := open()
+	if err != nil {
+		return 0, "", err
+	}
+	defer content.Close() //nolint:errcheck
+
+	h := sha256.New()
+	size, err := io.Copy(h, content)
+	if err != nil {
+		return 0, "", fmt.Errorf("hashing file contents: %w", err)
+	}
+
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// createFile writes the content into a new or truncated file
+func (e Extractor) createFile(destPath string, src *ManifestSource, content io.Reader) (err error) {
+	dest, err := os.Create(destPath) //#nosec:G304 // Intended to create files at given location
+	if err != nil {
+		return fmt.Errorf("creating destination file: %w", err)
+	}
+
+	// Only hash the content if it needs to be recorded
+	var (
+		h = sha256.New()
+		w = io.Writer(dest)
+	)
+	if e.Manifest != nil {
+		w = io.MultiWriter(dest, h)
+	}
+
+	defer func() {
+		if cerr := dest.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("closing destination file: %w", cerr)
+		}
+
+		if err == nil {
+			err = e.recordFile(destPath, hex.EncodeToString(h.Sum(nil)), src)
+		}
+	}()
+
+	if _, err = io.Copy(w, content); err != nil {
+		return fmt.Errorf("writing file contents: %w", err)
+	}
+
+	return nil
+}
+
+// recordFile adds the written file to the manifest if set
+func (e Extractor) recordFile(destPath, sum string, src *ManifestSource) error {
+	if e.Manifest == nil {
+		return nil
+	}
+
+	name, err := e.manifestName(destPath)
+	if err != nil {
+		return err
+	}
+
+	return e.Manifest.record(name, destPath, sum, src)
+}
+
+// sameContent reports whether the existing file has the given size
+// and hash. The hash from the manifest is used if the file was not
+// modified after it was recorded.
+func (e Extractor) sameContent(destPath string, size int64, sum string, src *ManifestSource) (bool, error) {
+	info, err := os.Lstat(destPath)
+	if err != nil {
+		return false, fmt.Errorf("getting file info: %w", err)
+	}
+
+	if !info.Mode().IsRegular() || info.Size() != size {
+		return false, nil
+	}
+
+	if entry, ok := e.manifestEntry(destPath, info); ok {
+		return entry.SHA256 == sum, nil
+	}
+
+	existing, err := hashFile(destPath)
+	if err != nil {
+		return false, fmt.Errorf("hashing existing file: %w", err)
+	}
+
+	if existing != sum {
+		return false, nil
+	}
+
+	// Remember the unchanged file to skip hashing it next time
+	return true, e.recordFile(destPath, sum, src)
+}
+
+// unchangedSource reports whether the file at destPath (or for
+// KeepBoth one of its numbered copies) was extracted from the same
+// archive entry according to the manifest and not modified since
+func (e Extractor) unchangedSource(destPath string, src *ManifestSource) bool {
+	if e.Manifest == nil || src == nil {
+		return false
+	}
+
+	target := destPath
+	for i := 1; i <= maxSuffix; i++ {
+		info, err := os.Lstat(target)
+		if err != nil {
+			return false
+		}
+
+		if entry, ok := e.manifestEntry(target, info); ok && entry.Source != nil && *entry.Source == *src {
+			return true
+		}
+
+		if e.Overwrite != KeepBoth {
+			return false
+		}
+
+		target = suffixedName(destPath, i)
+	}
+
+	return false
+}
+
+// manifestEntry returns the manifest entry of the file if the file
+// was not modified after it was recorded
+func (e Extractor) manifestEntry(destPath string, info os.FileInfo) (ManifestEntry, bool) {
+	if e.Manifest == nil || !info.Mode().IsRegular() {
+		return ManifestEntry{}, false
+	}
+
+	name, err := e.manifestName(destPath)
+	if err != nil {
+		return ManifestEntry{}, false
+	}
+
+	entry, ok := e.Manifest.lookup(name)
+	if !ok || entry.Size != info.Size() || !entry.ModTime.Equal(info.ModTime()) {
+		return ManifestEntry{}, false
+	}
+
+	return entry, true
+}
+
+// manifestName returns the slash separated path of the file relative
+// to the destination
+func (e Extractor) manifestName(destPath string) (string, error) {
+	rel, err := filepath.Rel(e.Dest, destPath)
+	if err != nil {
+		return "", fmt.Errorf("determining relative path: %w", err)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// exists reports whether anything exists at the given path
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return !errors.Is(err, os.ErrNotExist)
+}
+
+// suffixedName adds the numbered suffix before the extension
+func suffixedName(path string, n int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.%d%s", strings.TrimSuffix(path, ext), n, ext)
+}
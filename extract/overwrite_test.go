@@ -0,0 +1,175 @@
+package extract
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Luzifer/scs-extract/scs"
+)
+
+func TestOverwritePolicies(t *testing.T) {
+	r := openTestArchive(t, map[string]string{
+		"same.sii":    "same",
+		"changed.sii": "new content",
+	})
+
+	for policy, expect := range map[OverwritePolicy]map[string]string{
+		Overwrite:            {"same.sii": "same", "changed.sii": "new content"},
+		SkipExisting:         {"same.sii": "same", "changed.sii": "old"},
+		OverwriteIfDifferent: {"same.sii": "same", "changed.sii": "new content"},
+		KeepBoth:             {"same.sii": "same", "changed.sii": "old", "changed.1.sii": "new content"},
+	} {
+		dest := t.TempDir()
+		past := time.Now().Add(-time.Hour).Truncate(time.Second)
+		for name, content := range map[string]string{"same.sii": "same", "changed.sii": "old"} {
+			if err := os.WriteFile(filepath.Join(dest, name), []byte(content), 0o600); err != nil {
+				t.Fatalf("writing existing file: %s", err)
+			}
+			if err := os.Chtimes(filepath.Join(dest, name), past, past); err != nil {
+				t.Fatalf("setting modification time: %s", err)
+			}
+		}
+
+		manifest, err := LoadManifest(filepath.Join(dest, ManifestName))
+		if err != nil {
+			t.Fatalf("loading missing manifest: %s", err)
+		}
+
+		extract := func() (written, skipped []string) {
+			err := Extractor{
+				Dest:        dest,
+				Overwrite:   policy,
+				Manifest:    manifest,
+				OnExtracted: func(f *scs.File) { written = append(written, f.Name) },
+				OnSkipped:   func(f *scs.File) { skipped = append(skipped, f.Name) },
+			}.Extract(context.Background(), r.Files)
+			if err != nil {
+				t.Fatalf("%s: extracting: %s", policy, err)
+			}
+			return written, skipped
+		}
+
+		written, _ := extract()
+		for name, content := range expect {
+			data, err := os.ReadFile(filepath.Join(dest, name)) //#nosec:G304 // Test file
+			if err != nil || string(data) != content {
+				t.Errorf("%s: unexpected content of %s: %q (%v)", policy, name, data, err)
+			}
+		}
+
+		if policy != Overwrite && slices.Contains(written, "same.sii") {
+			t.Errorf("%s: unchanged file was written", policy)
+		}
+
+		// Unchanged files must not be touched at all
+		if info, err := os.Stat(filepath.Join(dest, "same.sii")); err != nil || (policy != Overwrite && !info.ModTime().Equal(past)) {
+			t.Errorf("%s: unchanged file was modified (%v)", policy, err)
+		}
+
+		// No temporary files must be left behind
+		if entries, err := os.ReadDir(dest); err != nil || len(entries) != len(expect) {
+			t.Errorf("%s: unexpected files in destination: %v (%v)", policy, entries, err)
+		}
+
+		if policy == SkipExisting {
+			continue
+		}
+
+		// Nothing changed since the last run, nothing must be written
+		if written, skipped := extract(); policy != Overwrite && (len(written) > 0 || len(skipped) != 2) { //nolint:mnd
+			t.Errorf("%s: unexpected re-run result: written=%v skipped=%v", policy, written, skipped)
+		}
+
+		if _, ok := manifest.Files["changed.sii"]; !ok && policy != KeepBoth {
+			t.Errorf("%s: written file missing in manifest: %v", policy, manifest.Files)
+		}
+	}
+}
+
+func TestManifestSkipsUnchangedEntries(t *testing.T) {
+	r := openTestArchive(t, map[string]string{
+		"def/city.sii":    "city",
+		"def/country.sii": "country",
+	})
+
+	for _, policy := range []OverwritePolicy{Overwrite, OverwriteIfDifferent, KeepBoth} {
+		dest := t.TempDir()
+		manifest, err := LoadManifest(filepath.Join(dest, ManifestName))
+		if err != nil {
+			t.Fatalf("loading missing manifest: %s", err)
+		}
+
+		// Transform is called whenever an entry is read from the archive
+		extract := func(files []*scs.File) (read []string) {
+			err := Extractor{
+				Dest:      dest,
+				Overwrite: policy,
+				Manifest:  manifest,
+				Transform: func(f *scs.File, r io.Reader) (io.Reader, error) {
+					read = append(read, f.Name)
+					return r, nil
+				},
+				Workers: 1,
+			}.Extract(context.Background(), files)
+			if err != nil {
+				t.Fatalf("%s: extracting: %s", policy, err)
+			}
+			// Entries compared before writing are read twice
+			return slices.Compact(read)
+		}
+
+		if read := extract(r.Files); len(read) != 2 { //nolint:mnd
+			t.Fatalf("%s: unexpected files read on first run: %v", policy, read)
+		}
+
+		if read := extract(r.Files); len(read) != 0 {
+			t.Errorf("%s: unchanged entries were read: %v", policy, read)
+		}
+
+		// Modified files are compared again
+		if err = os.WriteFile(filepath.Join(dest, "def", "city.sii"), []byte("modified"), 0o600); err != nil {
+			t.Fatalf("modifying file: %s", err)
+		}
+
+		if read := extract(r.Files); !slices.Equal(read, []string{"def/city.sii"}) {
+			t.Errorf("%s: unexpected files read after modification: %v", policy, read)
+		}
+
+		// Entries changed within the archive are not skipped
+		changed := openTestArchive(t, map[string]string{"def/country.sii": "other country"})
+		if read := extract(changed.Files); !slices.Equal(read, []string{"def/country.sii"}) {
+			t.Errorf("%s: changed entry was not read: %v", policy, read)
+		}
+	}
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	name := filepath.Join(t.TempDir(), ManifestName)
+
+	m := &Manifest{Files: map[string]ManifestEntry{"def/city.sii": {Size: 4, SHA256: "abc"}}}
+	if err := m.Save(name); err != nil {
+		t.Fatalf("saving manifest: %s", err)
+	}
+
+	loaded, err := LoadManifest(name)
+	if err != nil {
+		t.Fatalf("loading manifest: %s", err)
+	}
+
+	if entry := loaded.Files["def/city.sii"]; entry.Size != 4 || entry.SHA256 != "abc" {
+		t.Errorf("unexpected manifest entry: %+v", entry)
+	}
+
+	if _, err = ParseOverwritePolicy("if-different"); err != nil {
+		t.Errorf("parsing policy: %s", err)
+	}
+
+	if _, err = ParseOverwritePolicy("sometimes"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
@@ -0,0 +1,85 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrUnsafePath is returned for entries whose name would escape the
+// destination directory or whose destination path contains a symlink
+var ErrUnsafePath = errors.New("unsafe path")
+
+// SafeName converts the name of an archive entry into a clean, slash
+// separated path relative to the destination. Leading slashes (SCS
+// references are absolute to the archive root) and redundant elements
+// are removed. Names containing ".." elements as well as names
+// containing backslashes, colons (drive letters, alternate data
+// streams) or NUL bytes are rejected with ErrUnsafePath.
+func SafeName(name string) (string, error) {
+	if strings.ContainsAny(name, "\\:\x00") {
+		return "", fmt.Errorf("%w: %q contains invalid characters", ErrUnsafePath, name)
+	}
+
+	if slices.Contains(strings.Split(name, "/"), "..") {
+		return "", fmt.Errorf("%w: %q escapes the destination", ErrUnsafePath, name)
+	}
+
+	clean := path.Clean("/" + name)[1:]
+	if clean == "" {
+		return "", fmt.Errorf("%w: %q has no name", ErrUnsafePath, name)
+	}
+
+	return clean, nil
+}
+
+// safeDestPath returns the path to write the named entry to within
+// dest. All parent directories below dest are created without
+// following symlinks: existing symlinks (as well as a symlink at the
+// destination path itself) cause an ErrUnsafePath.
+func safeDestPath(dest, name string) (string, error) {
+	clean, err := SafeName(name)
+	if err != nil {
+		return "", err
+	}
+
+	elems := strings.Split(clean, "/")
+	current := dest
+	for _, elem := range elems[:len(elems)-1] {
+		current = filepath.Join(current, elem)
+
+		info, err := os.Lstat(current)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			if err = os.Mkdir(current, dirPermissions); err != nil && !errors.Is(err, os.ErrExist) {
+				return "", fmt.Errorf("creating directory: %w", err)
+			}
+			// Another worker might have created it in the meantime, check again
+			if info, err = os.Lstat(current); err != nil {
+				return "", fmt.Errorf("checking directory: %w", err)
+			}
+
+		case err != nil:
+			return "", fmt.Errorf("checking directory: %w", err)
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("%w: %s is a symlink", ErrUnsafePath, current)
+		}
+
+		if !info.IsDir() {
+			return "", fmt.Errorf("%s is not a directory", current)
+		}
+	}
+
+	destPath := filepath.Join(current, elems[len(elems)-1])
+	if info, err := os.Lstat(destPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return "", fmt.Errorf("%w: %s is a symlink", ErrUnsafePath, destPath)
+	}
+
+	return destPath, nil
+}
@@ -0,0 +1,90 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Luzifer/scs-extract/scs"
+)
+
+func TestSafeName(t *testing.T) {
+	for name, expect := range map[string]string{
+		"def/city.sii":       "def/city.sii",
+		"/def/city.sii":      "def/city.sii",
+		"def//./city.sii":    "def/city.sii",
+		"../etc/passwd":      "",
+		"def/../../passwd":   "",
+		"def/..":             "",
+		"..\\windows\\x.dll": "",
+		"C:/windows/x.dll":   "",
+		"def/city.sii\x00":   "",
+		"/":                  "",
+		"":                   "",
+	} {
+		got, err := SafeName(name)
+		switch {
+		case expect == "" && !errors.Is(err, ErrUnsafePath):
+			t.Errorf("%q: expected ErrUnsafePath, got %q / %v", name, got, err)
+		case expect != "" && (err != nil || got != expect):
+			t.Errorf("%q: expected %q, got %q / %v", name, expect, got, err)
+		}
+	}
+}
+
+func TestExtractRejectsUnsafe(t *testing.T) {
+	dest, outside := t.TempDir(), t.TempDir()
+
+	if err := os.Symlink(outside, filepath.Join(dest, "def")); err != nil {
+		t.Skipf("creating symlink: %s", err)
+	}
+
+	if err := os.Symlink(filepath.Join(outside, "target.sii"), filepath.Join(dest, "link.sii")); err != nil {
+		t.Fatalf("creating symlink: %s", err)
+	}
+
+	// Entries without archive data are extracted as empty files
+	files := []*scs.File{
+		{Name: "../escape.sii"},
+		{Name: "/safe.sii"},
+		{Name: "def/city.sii"},
+		{Name: "link.sii"},
+	}
+
+	var rejected, extracted []string
+	err := Extractor{
+		Dest:        dest,
+		OnExtracted: func(f *scs.File) { extracted = append(extracted, f.Name) },
+		OnRejected: func(f *scs.File, err error) {
+			if !errors.Is(err, ErrUnsafePath) {
+				t.Errorf("unexpected rejection reason for %s: %s", f.Name, err)
+			}
+			rejected = append(rejected, f.Name)
+		},
+	}.Extract(context.Background(), files)
+	if err != nil {
+		t.Fatalf("extracting: %s", err)
+	}
+
+	if len(extracted) != 1 || extracted[0] != "/safe.sii" {
+		t.Errorf("unexpected extracted files: %v", extracted)
+	}
+
+	if len(rejected) != 3 { //nolint:mnd
+		t.Errorf("unexpected rejected files: %v", rejected)
+	}
+
+	if _, err = os.Stat(filepath.Join(dest, "safe.sii")); err != nil {
+		t.Errorf("expected safe file to be extracted: %s", err)
+	}
+
+	if entries, _ := os.ReadDir(outside); len(entries) > 0 {
+		t.Errorf("files were written outside the destination: %v", entries)
+	}
+
+	if err = (Extractor{Dest: dest}).Extract(context.Background(), files[:1]); !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("expected unsafe files to fail without OnRejected, got %v", err)
+	}
+}
@@ -5,9 +5,10 @@ go 1.22
 toolchain go1.23.2
 
 require (
-	github.com/Luzifer/go_helpers/v2 v2.25.0
 	github.com/Luzifer/rconfig/v2 v2.5.2
+	github.com/klauspost/compress v1.18.0
 	github.com/sirupsen/logrus v1.9.3
+	golang.org/x/sync v0.10.0
 )
 
 require (
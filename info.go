@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Luzifer/scs-extract/scs"
+)
+
+// infoArchive prints a summary of every archive (or of every archive
+// within a game directory) or, if files are given, the details of the
+// effective version of these files
+func infoArchive(archive string, names []string) (err error) {
+	m, err := openLayers(archive)
+	if err != nil {
+		return err
+	}
+	defer m.Close() //nolint:errcheck
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd
+	defer func() {
+		if ferr := w.Flush(); ferr != nil && err == nil {
+			err = fmt.Errorf("flushing output: %w", ferr)
+		}
+	}()
+
+	if len(names) == 0 {
+		for i, l := range m.Layers() {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			writeLayerInfo(w, l)
+		}
+		return nil
+	}
+
+	for i, name := range names {
+		l, f, err := m.Provider(strings.TrimPrefix(name, "/"))
+		if err != nil {
+			return fmt.Errorf("file %s not found", name)
+		}
+
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if err = writeFileInfo(w, l, f); err != nil {
+			return fmt.Errorf("describing %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func writeLayerInfo(w io.Writer, l *scs.Layer) {
+	var (
+		files, dirs      int
+		size, storedSize uint64
+	)
+
+	for _, f := range l.Reader.Files {
+		if f.IsDirectory {
+			dirs++
+			continue
+		}
+		files++
+		size += uint64(f.Size)
+		storedSize += uint64(f.CompressedSize)
+	}
+
+	fmt.Fprintf(w, "Archive:\t%s\n", l.Name)
+	fmt.Fprintf(w, "Format:\t%s\n", l.Reader.Format())
+	fmt.Fprintf(w, "Entries:\t%d (%d files, %d directories)\n", len(l.Reader.Files), files, dirs)
+	fmt.Fprintf(w, "Size:\t%d bytes (%d bytes stored)\n", size, storedSize)
+	fmt.Fprintf(w, "Unnamed:\t%d\n", len(l.Reader.Unnamed()))
+	fmt.Fprintf(w, "Warnings:\t%d\n", len(l.Reader.Warnings))
+}
+
+func writeFileInfo(w io.Writer, l *scs.Layer, f *scs.File) error {
+	fmt.Fprintf(w, "Name:\t%s\n", f.Name)
+	fmt.Fprintf(w, "Archive:\t%s\n", l.Name)
+	fmt.Fprintf(w, "Hash:\t%016x\n", f.Hash)
+
+	if f.IsDirectory {
+		fmt.Fprintf(w, "Type:\tdirectory\n")
+		return nil
+	}
+
+	fileType, err := f.Sniff()
+	if err != nil {
+		return fmt.Errorf("detecting file type: %w", err)
+	}
+
+	fmt.Fprintf(w, "Type:\t%s\n", fileType)
+	fmt.Fprintf(w, "Metadata:\t%s\n", f.MetadataType())
+	fmt.Fprintf(w, "Size:\t%d bytes\n", f.Size)
+	fmt.Fprintf(w, "Stored size:\t%d bytes (compressed: %t)\n", f.CompressedSize, f.IsCompressed)
+	fmt.Fprintf(w, "Offset:\t%d\n", f.Offset())
+
+	if tex := f.TextureInfo(); tex != nil {
+		fmt.Fprintf(w, "Texture:\t%dx%d %s, %d mips, %d faces (cube: %t)\n",
+			tex.Width, tex.Height, tex.Format, tex.MipCount, tex.Faces, tex.IsCube)
+		fmt.Fprintf(w, "Alignment:\tpitch %d, image %d\n", tex.PitchAlignment, tex.ImageAlignment)
+	}
+
+	if s := f.Sampler; s != nil {
+		fmt.Fprintf(w, "Sampler:\tmag %s, min %s, mip %s, address %s/%s/%s\n",
+			s.MagFilter, s.MinFilter, s.MipFilter, s.AddressU, s.AddressV, s.AddressW)
+	}
+
+	if f.Mips != nil {
+		if f.Mips.Proxy != 0 {
+			fmt.Fprintf(w, "Mip proxy:\t%016x\n", f.Mips.Proxy)
+		}
+		for _, c := range f.Mips.Chunks {
+			fmt.Fprintf(w, "Mips %d-%d:\t%d bytes stored (compressed: %t)\n",
+				c.FirstMip, c.FirstMip+c.MipCount-1, c.CompressedSize, c.IsCompressed)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+// Package listing renders the file lists of SCS archives in human and
+// machine readable formats
+package listing
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/Luzifer/scs-extract/scs"
+)
+
+// Available output formats
+const (
+	FormatPlain  Format = "plain"
+	FormatJSON   Format = "json"
+	FormatNDJSON Format = "ndjson"
+	FormatCSV    Format = "csv"
+)
+
+type (
+	// Format specifies how the listing is rendered
+	Format string
+
+	// Entry is the machine readable representation of a File. The
+	// field names are part of the output schema and must stay stable.
+	Entry struct {
+		Name           string `json:"name"`
+		Hash           string `json:"hash"`
+		Size           uint32 `json:"size"`
+		CompressedSize uint32 `json:"compressed_size"`
+		Compressed     bool   `json:"compressed"`
+		Directory      bool   `json:"directory"`
+		MetadataType   string `json:"metadata_type"`
+		Offset         uint64 `json:"offset"`
+	}
+)
+
+var csvHeader = []string{"name", "hash", "size", "compressed_size", "compressed", "directory", "metadata_type", "offset"}
+
+// NewEntry creates the Entry for the given File
+func NewEntry(f *scs.File) Entry {
+	return Entry{
+		Name:           f.Name,
+		Hash:           fmt.Sprintf("%016x", f.Hash),
+		Size:           f.Size,
+		CompressedSize: f.CompressedSize,
+		Compressed:     f.IsCompressed,
+		Directory:      f.IsDirectory,
+		MetadataType:   f.MetadataType(),
+		Offset:         f.Offset(),
+	}
+}
+
+// ParseFormat validates the given format name
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case FormatPlain, FormatJSON, FormatNDJSON, FormatCSV:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q", name)
+	}
+}
+
+// Write renders the given files sorted by name into w. The plain
+// format only contains the names of the files while directories and
+// entries without name are omitted, all other formats contain all
+// fields of the Entry (entries without name are identified by their
+// hash).
+func Write(w io.Writer, format Format, files []*scs.File) error {
+	entries := make([]Entry, 0, len(files))
+	for _, f := range files {
+		switch {
+		case f.IsRoot():
+			// The root directory is not listed
+			continue
+		case format == FormatPlain && (f.IsDirectory || f.Name == ""):
+			continue
+		}
+		entries = append(entries, NewEntry(f))
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].Hash < entries[j].Hash
+	})
+
+	switch format {
+	case FormatPlain:
+		for _, e := range entries {
+			if _, err := fmt.Fprintln(w, e.Name); err != nil {
+				return fmt.Errorf("writing entry: %w", err)
+			}
+		}
+
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(entries); err != nil {
+			return fmt.Errorf("encoding entries: %w", err)
+		}
+
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return fmt.Errorf("encoding entry: %w", err)
+			}
+		}
+
+	case FormatCSV:
+		return writeCSV(w, entries)
+
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+
+	return nil
+}
+
+func writeCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("writing header: %w", err)
+	}
+
+	for _, e := range entries {
+		if err := cw.Write([]string{
+			e.Name,
+			e.Hash,
+			strconv.FormatUint(uint64(e.Size), 10),
+			strconv.FormatUint(uint64(e.CompressedSize), 10),
+			strconv.FormatBool(e.Compressed),
+			strconv.FormatBool(e.Directory),
+			e.MetadataType,
+			strconv.FormatUint(e.Offset, 10),
+		}); err != nil {
+			return fmt.Errorf("writing entry: %w", err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("flushing csv: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package listing
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Luzifer/scs-extract/scs"
+)
+
+var testFiles = []*scs.File{
+	{Name: "def/city.sii", Hash: 0xabc, Size: 120, CompressedSize: 80, IsCompressed: true},
+	{Name: "def", Hash: 0x2, Size: 13, CompressedSize: 13, IsDirectory: true},
+	{Name: "a, b.txt", Hash: 0x3, Size: 5, CompressedSize: 5},
+	{Name: "", Hash: 0x4, Size: 7, CompressedSize: 7},
+}
+
+func TestWrite(t *testing.T) {
+	// The root directory can only be created by a reader
+	r, err := scs.NewDirReader(t.TempDir())
+	if err != nil {
+		t.Fatalf("reading directory: %s", err)
+	}
+	files := append(testFiles, r.Files...) //nolint:gocritic // Intended to create a new slice
+
+	for format, expect := range map[Format]string{
+		FormatPlain: "a, b.txt\ndef/city.sii\n",
+
+		FormatJSON: `[
+  {
+    "name": "",
+    "hash": "0000000000000004",
+    "size": 7,
+    "compressed_size": 7,
+    "compressed": false,
+    "directory": false,
+    "metadata_type": "plain",
+    "offset": 0
+  },
+  {
+    "name": "a, b.txt",
+    "hash": "0000000000000003",
+    "size": 5,
+    "compressed_size": 5,
+    "compressed": false,
+    "directory": false,
+    "metadata_type": "plain",
+    "offset": 0
+  },
+  {
+    "name": "def",
+    "hash": "0000000000000002",
+    "size": 13,
+    "compressed_size": 13,
+    "compressed": false,
+    "directory": true,
+    "metadata_type": "directory",
+    "offset": 0
+  },
+  {
+    "name": "def/city.sii",
+    "hash": "0000000000000abc",
+    "size": 120,
+    "compressed_size": 80,
+    "compressed": true,
+    "directory": false,
+    "metadata_type": "plain",
+    "offset": 0
+  }
+]
+`,
+
+		FormatNDJSON: `{"name":"","hash":"0000000000000004","size":7,"compressed_size":7,"compressed":false,"directory":false,"metadata_type":"plain","offset":0}
+{"name":"a, b.txt","hash":"0000000000000003","size":5,"compressed_size":5,"compressed":false,"directory":false,"metadata_type":"plain","offset":0}
+{"name":"def","hash":"0000000000000002","size":13,"compressed_size":13,"compressed":false,"directory":true,"metadata_type":"directory","offset":0}
+{"name":"def/city.sii","hash":"0000000000000abc","size":120,"compressed_size":80,"compressed":true,"directory":false,"metadata_type":"plain","offset":0}
+`,
+
+		FormatCSV: `name,hash,size,compressed_size,compressed,directory,metadata_type,offset
+,0000000000000004,7,7,false,false,plain,0
+"a, b.txt",0000000000000003,5,5,false,false,plain,0
+def,0000000000000002,13,13,false,true,directory,0
+def/city.sii,0000000000000abc,120,80,true,false,plain,0
+`,
+	} {
+		buf := new(bytes.Buffer)
+		if err := Write(buf, format, files); err != nil {
+			t.Fatalf("writing %s: %s", format, err)
+		}
+
+		if buf.String() != expect {
+			t.Errorf("unexpected %s output:\n%s", format, buf.String())
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if _, err := ParseFormat("ndjson"); err != nil {
+		t.Errorf("parsing valid format: %s", err)
+	}
+
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
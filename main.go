@@ -1,48 +1,131 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"path"
+	"path/filepath"
+	"strings"
 
-	"github.com/Luzifer/go_helpers/v2/str"
-	"github.com/Luzifer/rconfig/v2"
+	"github.com/Luzifer/scs-extract/diff"
+	"github.com/Luzifer/scs-extract/extract"
+	"github.com/Luzifer/scs-extract/listing"
 	"github.com/Luzifer/scs-extract/scs"
+	"github.com/Luzifer/scs-extract/sii"
 	"github.com/sirupsen/logrus"
 )
 
-const dirPermissions = 0x750
+type (
+	// ArchiveOptions control how archives are opened
+	ArchiveOptions struct {
+		Dictionary   []string `flag:"dictionary" default:"" description:"Files containing paths (one per line) to recover names of unlisted entries from (implies --recover-names)"`
+		Lenient      bool     `flag:"lenient" default:"false" description:"Skip entries with unknown or broken metadata instead of failing to read the archive"`
+		RecoverNames bool     `flag:"recover-names" default:"false" description:"Recover names of entries not reachable through directory listings, unknown entries are placed in _unknown/"`
+	}
+
+	// DecodeOptions control how file contents are read
+	DecodeOptions struct {
+		DecodeSII bool `flag:"decode-sii" default:"false" description:"Decode encrypted (ScsC) and binary (BSII) SII files while extracting, printing, searching or comparing"`
+	}
+
+	// DiffOptions control the output of the diff command
+	DiffOptions struct {
+		Unified bool `flag:"unified,u" default:"false" description:"Show unified diffs of modified text files (.sii, .sui, .mat) when comparing archives"`
+	}
+
+	// ExtractOptions control where and how files are extracted
+	ExtractOptions struct {
+		Dest      string `flag:"dest,d" default:"." description:"Path prefix to use to extract files to"`
+		Manifest  bool   `flag:"manifest" default:"false" description:"Record extracted files in a manifest within the destination to detect unchanged files on re-extraction"`
+		Overwrite string `flag:"overwrite" default:"overwrite" description:"How to handle existing files (overwrite, skip, if-different, keep-both)"`
+		To        string `flag:"to" default:"" description:"Extract files into an archive (tar, tar.gz, tar.zst or zip by extension) at this path or - for stdout instead of --dest"`
+		ToFormat  string `flag:"to-format" default:"" description:"Format of the archive written by --to (tar, tar.gz, tar.zst, zip), defaults to tar for stdout"`
+		Workers   int    `flag:"workers,j" default:"0" description:"Number of files to extract in parallel (0 = number of CPUs)"`
+	}
+
+	// ListOptions control the output of the list command
+	ListOptions struct {
+		Output string `flag:"output,o" default:"plain" description:"Format of the file listing (plain, json, ndjson, csv)"`
+	}
+
+	// LogOptions are available for all commands
+	LogOptions struct {
+		LogLevel string `flag:"log-level" default:"info" description:"Log level (debug, info, warn, error, fatal)"`
+	}
+
+	// SearchOptions control the matching and output of the search
+	// command
+	SearchOptions struct {
+		FilesWithMatches bool `flag:"files-with-matches,l" default:"false" description:"Only print the names of files containing matches"`
+		IgnoreCase       bool `flag:"ignore-case,i" default:"false" description:"Match the expression case insensitive"`
+	}
+
+	// SelectOptions control which files of the archive are used
+	SelectOptions struct {
+		Exclude []string `flag:"exclude" default:"" description:"Skip files matching these patterns (glob, directory or exact name)"`
+		Regex   []string `flag:"regex" default:"" description:"Select files matching these regular expressions"`
+	}
+)
+
+const archivePermissions = 0o644
 
 var (
+	// cfg contains the options of all commands. Commands only parse
+	// their own option groups into it, invocations without command
+	// parse all of them.
 	cfg = struct {
-		Dest           string `flag:"dest,d" default:"." description:"Path prefix to use to extract files to"`
-		Extract        bool   `flag:"extract,x" default:"false" description:"Extract files (if not given files are just listed)"`
-		LogLevel       string `flag:"log-level" default:"info" description:"Log level (debug, info, warn, error, fatal)"`
-		VersionAndExit bool   `flag:"version" default:"false" description:"Prints current version and exits"`
+		ArchiveOptions
+		DecodeOptions
+		DiffOptions
+		ExtractOptions
+		ListOptions
+		LogOptions
+		SearchOptions
+		SelectOptions
+
+		Extract        bool `flag:"extract,x" default:"false" description:"Extract files (if not given files are just listed)"`
+		VersionAndExit bool `flag:"version" default:"false" description:"Prints current version and exits"`
 	}{}
 
 	version = "dev"
 )
 
-func initApp() (err error) {
-	if err = rconfig.ParseAndValidate(&cfg); err != nil {
-		return fmt.Errorf("parsing CLI options: %w", err)
+// initApp parses the CLI options and returns the command to run
+// together with its positional arguments
+func initApp() (cmd *command, args []string, err error) {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "help", "-h", "--help":
+			showHelp(os.Args[2:])
+		}
+
+		cmd = findCommand(os.Args[1])
+	}
+
+	if cmd != nil {
+		args, err = parseCommand(cmd)
+	} else {
+		cmd, args, err = parseLegacy()
+	}
+	if err != nil {
+		return nil, nil, err
 	}
 
 	l, err := logrus.ParseLevel(cfg.LogLevel)
 	if err != nil {
-		return fmt.Errorf("parsing log-level: %w", err)
+		return nil, nil, fmt.Errorf("parsing log-level: %w", err)
 	}
 	logrus.SetLevel(l)
 
-	return nil
+	return cmd, args, nil
 }
 
-//nolint:gocyclo // simple loop routine, fine to understand
 func main() {
-	var err error
-	if err = initApp(); err != nil {
+	cmd, args, err := initApp()
+	if err != nil {
 		logrus.WithError(err).Fatal("initializing app")
 	}
 
@@ -51,91 +134,482 @@ func main() {
 		os.Exit(0)
 	}
 
-	var (
-		archive string
-		extract []string
-	)
+	if len(args) < cmd.minArgs || (cmd.maxArgs >= 0 && len(args) > cmd.maxArgs) {
+		logrus.Fatalf("usage: scs-extract %s %s", cmd.name, cmd.args)
+	}
+
+	if err = cmd.run(args); err != nil {
+		logrus.WithError(err).Fatal(cmd.action)
+	}
+}
+
+// listFiles writes the listing of the selected files to stdout
+func listFiles(archive string, patterns []string) error {
+	selector, err := buildSelector(patterns)
+	if err != nil {
+		return fmt.Errorf("parsing file selection: %w", err)
+	}
+
+	outputFormat, err := listing.ParseFormat(cfg.Output)
+	if err != nil {
+		return fmt.Errorf("parsing output format: %w", err)
+	}
+
+	files, archiveCloser, err := openArchive(archive)
+	if err != nil {
+		return fmt.Errorf("opening archive: %w", err)
+	}
+	defer archiveCloser.Close() //nolint:errcheck
+
+	return listing.Write(os.Stdout, outputFormat, selector.Filter(files)) //nolint:wrapcheck
+}
+
+// extractArchive extracts the selected files into the destination or
+// into the archive given by --to
+func extractArchive(archive string, patterns []string) error {
+	selector, err := buildSelector(patterns)
+	if err != nil {
+		return fmt.Errorf("parsing file selection: %w", err)
+	}
+
+	if cfg.To == "" {
+		// The Extractor creates the destination, but it must not be a file
+		if info, err := os.Stat(cfg.Dest); err == nil && !info.IsDir() {
+			return fmt.Errorf("destination %s exists and is no directory", cfg.Dest)
+		}
+	}
 
-	switch len(rconfig.Args()) {
-	case 1:
-		// No positional arguments
-		logrus.Fatal("no SCS archive given")
+	files, archiveCloser, err := openArchive(archive)
+	if err != nil {
+		return fmt.Errorf("opening archive: %w", err)
+	}
+	defer archiveCloser.Close() //nolint:errcheck
 
-	case 2: //nolint:mnd
-		archive = rconfig.Args()[1]
+	var selected []*scs.File
+	for _, f := range selector.Filter(files) {
+		if f.Name == "" {
+			// Entries without name cannot be extracted, use
+			// --recover-names to extract them as _unknown/<hash>
+			continue
+		}
+		selected = append(selected, f)
+	}
 
-	default:
-		archive = rconfig.Args()[1]
-		extract = rconfig.Args()[2:]
+	if cfg.To != "" {
+		// Extract into an archive instead of the destination directory
+		return extractToArchive(selected)
 	}
 
-	f, err := os.Open(archive) //#nosec:G304 // Intended to open arbitrary files
+	return extractFiles(selected)
+}
+
+// buildSelector creates the file selection from the positional
+// patterns and the --regex and --exclude flags
+func buildSelector(patterns []string) (*scs.Selector, error) {
+	selector, err := scs.NewSelector(patterns...)
 	if err != nil {
-		logrus.WithError(err).Fatal("opening input file")
+		return nil, fmt.Errorf("adding patterns: %w", err)
+	}
+
+	for _, expr := range cfg.Regex {
+		if err = selector.IncludeRegexp(expr); err != nil {
+			return nil, fmt.Errorf("adding regex: %w", err)
+		}
+	}
+
+	for _, pattern := range cfg.Exclude {
+		if err = selector.Exclude(pattern); err != nil {
+			return nil, fmt.Errorf("adding exclude: %w", err)
+		}
 	}
-	defer f.Close() //nolint:errcheck // will be closed by program exit
 
-	r, err := scs.NewReader(f)
+	return selector, nil
+}
+
+// catFiles writes the content of the named files within the archive
+// to stdout, decoding SII files if requested
+func catFiles(archive string, names []string) (err error) {
+	files, archiveCloser, err := openArchive(archive)
 	if err != nil {
-		logrus.WithError(err).Fatal("reading SCS file headers")
+		return fmt.Errorf("opening archive: %w", err)
 	}
+	defer archiveCloser.Close() //nolint:errcheck
+
+	byName := make(map[string]*scs.File, len(files))
+	for _, f := range files {
+		byName[f.Name] = f
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer func() {
+		if ferr := out.Flush(); ferr != nil && err == nil {
+			err = fmt.Errorf("flushing output: %w", ferr)
+		}
+	}()
+
+	for _, name := range names {
+		f, ok := byName[strings.TrimPrefix(name, "/")]
+		switch {
+		case !ok:
+			return fmt.Errorf("file %s not found", name)
+		case f.IsDirectory:
+			return fmt.Errorf("%s is a directory", name)
+		}
 
-	logrus.WithField("no_files", len(r.Files)).Debug("opened archive")
+		if err = catFile(out, f); err != nil {
+			return fmt.Errorf("writing %s: %w", name, err)
+		}
+	}
 
-	destInfo, err := os.Stat(cfg.Dest)
+	return nil
+}
+
+func catFile(w io.Writer, f *scs.File) error {
+	rc, err := f.Open()
 	if err != nil {
-		if !os.IsNotExist(err) {
-			logrus.WithError(err).Fatal("accessing destination")
+		return fmt.Errorf("opening file: %w", err)
+	}
+	defer rc.Close() //nolint:errcheck
+
+	var content io.Reader = rc
+	if cfg.DecodeSII {
+		if content, err = sii.NewDecoder(rc); err != nil {
+			return fmt.Errorf("decoding file: %w", err)
 		}
+	}
+
+	if _, err = io.Copy(w, content); err != nil {
+		return fmt.Errorf("copying content: %w", err)
+	}
+
+	return nil
+}
 
-		if err := os.MkdirAll(cfg.Dest, dirPermissions); err != nil {
-			logrus.WithError(err).Fatal("creating destination directory")
+// diffArchives prints the files added, removed or modified between
+// the old and new archive (or game directory)
+func diffArchives(oldArchive, newArchive string) error {
+	oldFiles, oldCloser, err := openArchive(oldArchive)
+	if err != nil {
+		return fmt.Errorf("opening old archive: %w", err)
+	}
+	defer oldCloser.Close() //nolint:errcheck
+
+	newFiles, newCloser, err := openArchive(newArchive)
+	if err != nil {
+		return fmt.Errorf("opening new archive: %w", err)
+	}
+	defer newCloser.Close() //nolint:errcheck
+
+	opts := diff.Options{Unified: cfg.Unified}
+	if cfg.DecodeSII {
+		opts.Transform = func(_ *scs.File, r io.Reader) (io.Reader, error) {
+			return sii.NewDecoder(r) //nolint:wrapcheck
 		}
 	}
 
-	if destInfo != nil && !destInfo.IsDir() {
-		logrus.Fatal("destination exists and is no directory")
+	changes, err := diff.Compare(oldFiles, newFiles, opts)
+	if err != nil {
+		return fmt.Errorf("comparing files: %w", err)
 	}
 
-	for _, file := range r.Files {
-		if !str.StringInSlice(file.Name, extract) && len(extract) > 0 {
-			// Files to extract are given but this is not mentioned
-			continue
+	symbols := map[diff.Kind]string{diff.KindAdded: "A", diff.KindRemoved: "D", diff.KindModified: "M"}
+	for _, c := range changes {
+		fmt.Printf("%s %s\n", symbols[c.Kind], c.Name) //nolint:forbidigo // Intended to print change list
+		if c.Unified != "" {
+			fmt.Print(c.Unified) //nolint:forbidigo // Intended to print diff
 		}
+	}
 
-		if file.IsDirectory {
-			// Don't care about directories, if they contain files they will be created
-			continue
+	return nil
+}
+
+// openArchive opens the given archive or, when a directory is given,
+// all archives of the game installation within it as a merged view
+// with the files of later mounted archives overriding earlier ones
+func openArchive(archive string) ([]*scs.File, io.Closer, error) {
+	m, err := openLayers(archive)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, l := range m.Layers() {
+		logWarnings(l.Name, l.Reader)
+	}
+
+	files := m.Files()
+	if layers := m.Layers(); len(layers) == 1 && layers[0].Name == archive {
+		// Single archive: also list the entries without name
+		files = layers[0].Reader.Files
+	}
+
+	logrus.WithField("no_files", len(files)).Debug("opened archive")
+	return files, m, nil
+}
+
+// openLayers opens the given archive or all archives of the game
+// installation within the given directory as layers and recovers
+// names if requested
+func openLayers(archive string) (*scs.MultiReader, error) {
+	info, err := os.Stat(archive)
+	if err != nil {
+		return nil, fmt.Errorf("accessing archive: %w", err)
+	}
+
+	m := scs.NewMultiReader()
+	if info.IsDir() {
+		if m, err = scs.OpenGameDir(archive, readerOptions()...); err != nil {
+			return nil, fmt.Errorf("opening game directory: %w", err)
 		}
+	} else if err = m.AddArchive(archive, 0, readerOptions()...); err != nil {
+		return nil, fmt.Errorf("reading SCS file headers: %w", err)
+	}
+
+	var readers []*scs.Reader
+	for _, l := range m.Layers() {
+		logrus.WithFields(logrus.Fields{"archive": l.Name, "priority": l.Priority}).Debug("mounted archive")
+		readers = append(readers, l.Reader)
+	}
+
+	if err = maybeRecoverNames(readers); err != nil {
+		m.Close() //nolint:errcheck,gosec // Already in error state
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// readerOptions returns the options to open archives with
+func readerOptions() []scs.ReaderOption {
+	if cfg.Lenient {
+		return []scs.ReaderOption{scs.Lenient()}
+	}
+	return nil
+}
 
-		if !cfg.Extract {
-			// Not asked to extract, do not extract
-			fmt.Println(file.Name) //nolint:forbidigo // Intended to print file list
+// logWarnings logs the problems the reader skipped in lenient mode and
+// points to --recover-names for archives without directory listing
+func logWarnings(archive string, r *scs.Reader) {
+	for _, w := range r.Warnings {
+		if errors.Is(w, scs.ErrNoRootListing) {
+			if !recoveringNames() {
+				logrus.WithFields(logrus.Fields{"archive": archive, "unnamed": len(r.Unnamed())}).
+					Warn("archive has no directory listing, use --recover-names to access its entries")
+			}
 			continue
 		}
 
-		destPath := path.Join(cfg.Dest, file.Name)
-		if err := os.MkdirAll(path.Dir(destPath), dirPermissions); err != nil {
-			logrus.WithError(err).Fatal("creating directory")
+		logrus.WithField("archive", archive).WithError(w).Warn("skipped unreadable entry")
+	}
+}
+
+// extractFiles writes the selected files to the destination using the
+// overwrite policy and manifest given in the CLI options
+func extractFiles(files []*scs.File) (err error) {
+	ex := newExtractor()
+	if ex.Overwrite, err = extract.ParseOverwritePolicy(cfg.Overwrite); err != nil {
+		return fmt.Errorf("parsing overwrite policy: %w", err)
+	}
+
+	if cfg.Manifest {
+		manifestPath := filepath.Join(cfg.Dest, extract.ManifestName)
+		if ex.Manifest, err = extract.LoadManifest(manifestPath); err != nil {
+			return fmt.Errorf("loading manifest: %w", err)
 		}
 
-		src, err := file.Open()
+		defer func() {
+			// Also record the files written before an error occurred
+			if serr := ex.Manifest.Save(manifestPath); serr != nil && err == nil {
+				err = fmt.Errorf("saving manifest: %w", serr)
+			}
+		}()
+	}
+
+	return ex.Extract(context.Background(), files) //nolint:wrapcheck
+}
+
+// extractToArchive writes the selected files into the archive given
+// by --to (or to stdout for "-")
+func extractToArchive(files []*scs.File) (err error) {
+	format := extract.FormatTar
+	switch {
+	case cfg.ToFormat != "":
+		format, err = extract.ParseArchiveFormat(cfg.ToFormat)
+	case cfg.To != "-":
+		format, err = extract.ArchiveFormatFromName(cfg.To)
+	}
+	if err != nil {
+		return fmt.Errorf("determining archive format: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if cfg.To != "-" {
+		f, err := os.Create(cfg.To) //#nosec:G304 // Intended to create files at given location
 		if err != nil {
-			logrus.WithError(err).Fatal("opening file from archive")
+			return fmt.Errorf("creating archive: %w", err)
 		}
+		defer func() {
+			if cerr := f.Close(); cerr != nil && err == nil {
+				err = fmt.Errorf("closing archive: %w", cerr)
+			}
+		}()
+		w = f
+	}
+
+	return newExtractor().ExtractArchive(context.Background(), w, format, files) //nolint:wrapcheck
+}
+
+// newExtractor creates the Extractor logging its progress and decoding
+// SII files if requested
+func newExtractor() extract.Extractor {
+	ex := extract.Extractor{
+		Dest:    cfg.Dest,
+		Workers: cfg.Workers,
+		OnExtracted: func(file *scs.File) {
+			logrus.WithField("file", file.Name).Info("File extracted")
+		},
+		OnRejected: func(file *scs.File, err error) {
+			logrus.WithField("file", file.Name).WithError(err).Warn("File rejected")
+		},
+		OnSkipped: func(file *scs.File) {
+			logrus.WithField("file", file.Name).Debug("File skipped")
+		},
+	}
+
+	if cfg.DecodeSII {
+		ex.Transform = func(_ *scs.File, r io.Reader) (io.Reader, error) {
+			return sii.NewDecoder(r) //nolint:wrapcheck
+		}
+	}
+
+	return ex
+}
+
+// maybeRecoverNames runs the name recovery if requested
+func maybeRecoverNames(readers []*scs.Reader) error {
+	if !recoveringNames() {
+		return nil
+	}
+
+	if err := recoverNames(readers, cfg.Dictionary); err != nil {
+		return fmt.Errorf("recovering names: %w", err)
+	}
+
+	return nil
+}
 
-		dest, err := os.Create(destPath) //#nosec:G304 // Intended to create files at given location
+// recoveringNames reports whether names of unlisted entries are
+// recovered
+func recoveringNames() bool {
+	return cfg.RecoverNames || len(cfg.Dictionary) > 0
+}
+
+// packArchive creates a HashFS v2 archive containing all files within
+// the given source directory. The archive is written to a temporary
+// file first and moved into place on success.
+func packArchive(archive, src string) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(archive), "."+filepath.Base(archive)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating archive: %w", err)
+	}
+	defer func() {
 		if err != nil {
-			logrus.WithError(err).Fatal("creating destination file")
+			tmp.Close()           //nolint:errcheck,gosec // Already in error state
+			os.Remove(tmp.Name()) //nolint:errcheck,gosec // Already in error state
 		}
+	}()
 
-		if _, err = io.Copy(dest, src); err != nil {
-			logrus.WithError(err).WithField("name", file.Name).Fatal("Unable to write file contents")
+	// The archive might be written into the source directory and must
+	// not be packed into itself
+	var exclude []string
+	for _, p := range []string{archive, tmp.Name()} {
+		if rel, ok := pathWithin(src, p); ok {
+			exclude = append(exclude, rel)
 		}
+	}
+
+	w, err := scs.NewWriter(tmp)
+	if err != nil {
+		return fmt.Errorf("creating writer: %w", err)
+	}
+
+	if err = w.AddFSExcept(os.DirFS(src), exclude...); err != nil {
+		return fmt.Errorf("adding files: %w", err)
+	}
+
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
 
-		dest.Close() //nolint:errcheck,gosec,revive // Will be closed by program exit
-		src.Close()  //nolint:errcheck,gosec // Will be closed by program exit
+	// Temporary files are created private, archives are not
+	if err = tmp.Chmod(archivePermissions); err != nil {
+		return fmt.Errorf("setting permissions: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("closing archive: %w", err)
+	}
 
-		logrus.WithField("file", file.Name).Info("File extracted")
+	if err = os.Rename(tmp.Name(), archive); err != nil {
+		return fmt.Errorf("moving archive into place: %w", err)
 	}
+
+	logrus.WithField("archive", archive).Info("Archive packed")
+	return nil
+}
+
+// pathWithin returns the slash separated path of p relative to dir if
+// p is located within dir
+func pathWithin(dir, p string) (string, bool) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+
+	absPath, err := filepath.Abs(p)
+	if err != nil {
+		return "", false
+	}
+
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	return filepath.ToSlash(rel), true
+}
+
+// verifyArchive checks the integrity of the given archive or of all
+// archives within the given game directory and logs all problems found
+func verifyArchive(archive string) error {
+	m, err := openLayers(archive)
+	if err != nil {
+		return err
+	}
+	defer m.Close() //nolint:errcheck
+
+	var problems int
+	for _, l := range m.Layers() {
+		for _, w := range l.Reader.Warnings {
+			if errors.Is(w, scs.ErrNoRootListing) {
+				// Stripped listings are intended, not a defect
+				logrus.WithField("archive", l.Name).Warn(w.Error())
+				continue
+			}
+
+			logrus.WithField("archive", l.Name).Error(w.Error())
+			problems++
+		}
+
+		for _, p := range l.Reader.Verify() {
+			logrus.WithField("archive", l.Name).Error(p.Error())
+			problems++
+		}
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("found %d problems", problems)
+	}
+
+	logrus.Info("No problems found")
+	return nil
 }
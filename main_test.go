@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Luzifer/scs-extract/b0rkhash"
+	"github.com/Luzifer/scs-extract/extract"
+	"github.com/Luzifer/scs-extract/scs"
+	"github.com/sirupsen/logrus"
+)
+
+// messageHook collects the messages of all log entries
+type messageHook []string
+
+func (*messageHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (h *messageHook) Fire(e *logrus.Entry) error {
+	*h = append(*h, e.Message)
+	return nil
+}
+
+// packTestArchive writes a HashFS v2 archive containing the given
+// files and returns its path
+func packTestArchive(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	archive := filepath.Join(t.TempDir(), "test.scs")
+	f, err := os.Create(archive) //#nosec:G304 // Test file
+	if err != nil {
+		t.Fatalf("creating archive: %s", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	w, err := scs.NewWriter(f)
+	if err != nil {
+		t.Fatalf("creating writer: %s", err)
+	}
+
+	for name, content := range files {
+		if err = w.AddFile(name, strings.NewReader(content)); err != nil {
+			t.Fatalf("adding %s: %s", name, err)
+		}
+	}
+
+	if err = w.Close(); err != nil {
+		t.Fatalf("closing writer: %s", err)
+	}
+
+	return archive
+}
+
+// writeUnnamedArchive writes a HashFS v1 archive without directory
+// listings containing the file only known by the hash of its name
+// and returns its path
+func writeUnnamedArchive(t *testing.T, name, content string) string {
+	t.Helper()
+
+	type (
+		header struct {
+			Magic           [4]byte
+			Version         uint16
+			Salt            uint16
+			HashMethod      [4]byte
+			EntryCount      uint32
+			EntryTableStart uint32
+		}
+
+		entry struct {
+			Hash           uint64
+			Offset         uint64
+			Flags          uint32
+			CRC            uint32
+			Size           uint32
+			CompressedSize uint32
+		}
+	)
+
+	hdrSize := binary.Size(header{})
+	hdr := header{
+		Version:         1,
+		EntryCount:      1,
+		EntryTableStart: uint32(hdrSize + len(content)), //#nosec:G115 // Small test data
+	}
+	copy(hdr.Magic[:], "SCS#")
+	copy(hdr.HashMethod[:], "CITY")
+
+	buf := new(bytes.Buffer)
+	for _, v := range []any{
+		hdr,
+		[]byte(content),
+		entry{
+			Hash:           b0rkhash.CityHash64([]byte(name)),
+			Offset:         uint64(hdrSize),      //#nosec:G115 // Small test data
+			Size:           uint32(len(content)), //#nosec:G115 // Small test data
+			CompressedSize: uint32(len(content)), //#nosec:G115 // Small test data
+		},
+	} {
+		if err := binary.Write(buf, binary.LittleEndian, v); err != nil {
+			t.Fatalf("writing archive: %s", err)
+		}
+	}
+
+	archive := filepath.Join(t.TempDir(), "unnamed.scs")
+	if err := os.WriteFile(archive, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("writing archive: %s", err)
+	}
+
+	return archive
+}
+
+// captureStdout runs fn while collecting everything written to
+// os.Stdout
+func captureStdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+
+	out, err := os.Create(filepath.Join(t.TempDir(), "stdout")) //#nosec:G304 // Test file
+	if err != nil {
+		t.Fatalf("creating output file: %s", err)
+	}
+	defer out.Close() //nolint:errcheck
+
+	stdout := os.Stdout
+	os.Stdout = out
+	defer func() { os.Stdout = stdout }()
+
+	runErr := fn()
+
+	data, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatalf("reading output: %s", err)
+	}
+
+	return string(data), runErr
+}
+
+func TestCommands(t *testing.T) {
+	packed := packTestArchive(t, map[string]string{
+		"def/city.sii":    "SiiNunit\n{\ncity : .berlin {\n}\n}\n",
+		"def/country.sii": "SiiNunit\n{\ncountry : .germany {\n}\n}\n",
+		"manifest.sii":    "SiiNunit\n{\n}\n",
+	})
+	unnamed := writeUnnamedArchive(t, "def/city.sii", "SiiNunit\n{\ncity : .berlin {\n}\n}\n")
+
+	orig := cfg
+	for _, tc := range []struct {
+		name    string
+		setup   func()
+		args    []string
+		expect  []string // Lines contained in the output
+		wantErr bool     // Error reaching main, exits with status 1
+	}{
+		{
+			name:   "cat matching path",
+			args:   []string{"cat", packed, "def/city.sii", "/manifest.sii"},
+			expect: []string{"city : .berlin {", "SiiNunit"},
+		},
+		{
+			name:    "cat missing path",
+			args:    []string{"cat", packed, "def/missing.sii"},
+			wantErr: true,
+		},
+		{
+			name:    "cat directory",
+			args:    []string{"cat", packed, "def"},
+			wantErr: true,
+		},
+		{
+			name:    "cat unnamed only",
+			args:    []string{"cat", unnamed, "def/city.sii"},
+			wantErr: true,
+		},
+		{
+			name:   "info archive",
+			args:   []string{"info", packed},
+			expect: []string{"HashFS v2", "Entries:   5 (3 files, 2 directories)"},
+		},
+		{
+			name:   "info matching path",
+			args:   []string{"info", packed, "def/country.sii"},
+			expect: []string{"def/country.sii", "plain"},
+		},
+		{
+			name:    "info missing path",
+			args:    []string{"info", packed, "def/missing.sii"},
+			wantErr: true,
+		},
+		{
+			name:   "info unnamed only",
+			args:   []string{"info", unnamed},
+			expect: []string{"HashFS v1", "Unnamed:   1"},
+		},
+		{
+			name:   "search matching path",
+			args:   []string{"search", packed, `\.berlin`, "def/city.sii"},
+			expect: []string{"def/city.sii:3:city : .berlin {"},
+		},
+		{
+			name:   "search files with matches",
+			setup:  func() { cfg.FilesWithMatches = true },
+			args:   []string{"search", packed, "SiiNunit", "def/"},
+			expect: []string{"def/city.sii", "def/country.sii"},
+		},
+		{
+			name:   "search missing path",
+			args:   []string{"search", packed, "SiiNunit", "def/missing.sii"},
+			expect: nil,
+		},
+		{
+			name:    "search invalid expression",
+			args:    []string{"search", packed, "(", "def/"},
+			wantErr: true,
+		},
+		{
+			name:   "search unnamed only",
+			args:   []string{"search", unnamed, `\.berlin`},
+			expect: nil,
+		},
+		{
+			name:   "search unnamed only with recovered names",
+			setup:  func() { cfg.RecoverNames = true },
+			args:   []string{"search", unnamed, `\.berlin`},
+			expect: []string{"_unknown/", ".sii:3:city : .berlin {"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg = orig
+			defer func() { cfg = orig }()
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			cmd := findCommand(tc.args[0])
+			out, err := captureStdout(t, func() error { return cmd.run(tc.args[1:]) })
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, expect := range tc.expect {
+				if !strings.Contains(out, expect) {
+					t.Errorf("output does not contain %q:\n%s", expect, out)
+				}
+			}
+
+			if tc.expect == nil && out != "" {
+				t.Errorf("unexpected output:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestPackArchive(t *testing.T) {
+	src := t.TempDir()
+	archive := filepath.Join(t.TempDir(), "mod.scs")
+
+	// Archives without entries cannot be read, nothing must be written
+	if err := packArchive(archive, src); !errors.Is(err, scs.ErrEmptyArchive) {
+		t.Fatalf("expected empty archive error, got %v", err)
+	}
+
+	if entries, err := os.ReadDir(filepath.Dir(archive)); err != nil || len(entries) != 0 {
+		t.Fatalf("unexpected files left behind: %v (%v)", entries, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(src, "manifest.sii"), []byte("SiiNunit\n{\n}\n"), 0o600); err != nil {
+		t.Fatalf("writing source file: %s", err)
+	}
+
+	if err := packArchive(archive, src); err != nil {
+		t.Fatalf("packing archive: %s", err)
+	}
+
+	f, err := os.Open(archive) //#nosec:G304 // Test file
+	if err != nil {
+		t.Fatalf("opening archive: %s", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	r, err := scs.NewReader(f)
+	if err != nil {
+		t.Fatalf("reading packed archive: %s", err)
+	}
+
+	if data, err := r.ReadFile("manifest.sii"); err != nil || string(data) != "SiiNunit\n{\n}\n" {
+		t.Errorf("unexpected content of packed file: %q (%v)", data, err)
+	}
+}
+
+func TestExtractSkipsUnnamedEntries(t *testing.T) {
+	archive := writeUnnamedArchive(t, "def/city.sii", "SiiNunit\n{\n}\n")
+
+	orig := cfg
+	defer func() { cfg = orig }()
+
+	for _, tc := range []struct {
+		name    string
+		setup   func(dest string)
+		extract string // File expected in the destination
+	}{
+		{name: "without names", setup: func(dest string) { cfg.Dest = dest }},
+		{name: "into archive", setup: func(dest string) { cfg.To = filepath.Join(dest, "out.tar") }, extract: "out.tar"},
+		{name: "with recovered names", setup: func(dest string) { cfg.Dest, cfg.RecoverNames = dest, true }, extract: "_unknown"},
+	} {
+		cfg = orig
+		cfg.Overwrite = extract.Overwrite.String()
+		dest := t.TempDir()
+		tc.setup(dest)
+
+		// Unnamed entries must be skipped quietly instead of rejected
+		hook := new(messageHook)
+		logrus.AddHook(hook)
+
+		err := extractArchive(archive, nil)
+		logrus.StandardLogger().ReplaceHooks(logrus.LevelHooks{})
+		if err != nil {
+			t.Fatalf("%s: extracting: %s", tc.name, err)
+		}
+
+		if slices.Contains(*hook, "File rejected") {
+			t.Errorf("%s: unnamed entry was rejected: %v", tc.name, *hook)
+		}
+
+		entries, err := os.ReadDir(dest)
+		if err != nil {
+			t.Fatalf("%s: reading destination: %s", tc.name, err)
+		}
+		if (tc.extract == "" && len(entries) > 0) || (tc.extract != "" && (len(entries) != 1 || entries[0].Name() != tc.extract)) {
+			t.Errorf("%s: unexpected destination content: %v", tc.name, entries)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/Luzifer/scs-extract/scs"
+	"github.com/Luzifer/scs-extract/sii"
+	"github.com/sirupsen/logrus"
+)
+
+// maxRecoveryPasses limits how often the references of newly named
+// files are used to recover further names
+const maxRecoveryPasses = 10
+
+// noReferenceExtensions lists files not containing references worth
+// scanning for
+var noReferenceExtensions = []string{".bank", ".dds", ".jpg", ".ogg", ".pma", ".pmc", ".pmg", ".png"}
+
+// recoverNames names the entries of the given readers not reachable
+// through directory listings using the paths from the dictionary
+// files and the references found in all named files. Entries which
+// cannot be named are placed in the scs.UnknownDir.
+func recoverNames(readers []*scs.Reader, dictionaries []string) error {
+	var candidates []string
+	for _, dict := range dictionaries {
+		paths, err := readDictionary(dict)
+		if err != nil {
+			return fmt.Errorf("reading dictionary %s: %w", dict, err)
+		}
+		candidates = append(candidates, paths...)
+	}
+
+	scanned := make(map[*scs.File]bool)
+	for pass := 0; pass < maxRecoveryPasses; pass++ {
+		var recovered int
+		for _, r := range readers {
+			recovered += r.RecoverNames(candidates)
+		}
+
+		logrus.WithFields(logrus.Fields{"pass": pass, "recovered": recovered}).Debug("recovered names")
+		if recovered == 0 && pass > 0 {
+			break
+		}
+
+		candidates = nil
+		for _, r := range readers {
+			for _, f := range r.Files {
+				if scanned[f] || f.Name == "" || f.IsDirectory || slices.Contains(noReferenceExtensions, strings.ToLower(path.Ext(f.Name))) {
+					continue
+				}
+				scanned[f] = true
+
+				refs, err := fileReferences(f)
+				if err != nil {
+					logrus.WithError(err).WithField("file", f.Name).Debug("scanning for references")
+					continue
+				}
+				candidates = append(candidates, refs...)
+			}
+		}
+	}
+
+	for _, r := range readers {
+		if unnamed := len(r.Unnamed()); unnamed > 0 {
+			logrus.WithField("unnamed", unnamed).Warn("some names could not be recovered")
+		}
+		r.NameUnknown()
+	}
+
+	return nil
+}
+
+func fileReferences(f *scs.File) ([]string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening file: %w", err)
+	}
+	defer rc.Close() //nolint:errcheck
+
+	content, err := sii.NewDecoder(rc)
+	if err != nil {
+		return nil, fmt.Errorf("decoding file: %w", err)
+	}
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+
+	return scs.ReferencedPaths(f.Name, data), nil
+}
+
+// readDictionary reads a list of paths (one per line) to try as names
+// for the unnamed entries
+func readDictionary(name string) ([]string, error) {
+	f, err := os.Open(name) //#nosec:G304 // Intended to open arbitrary files
+	if err != nil {
+		return nil, fmt.Errorf("opening file: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	var paths []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if line := strings.TrimSpace(s.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			paths = append(paths, line)
+		}
+	}
+
+	if err = s.Err(); err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,356 @@
+package scs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+)
+
+const (
+	ddsHeaderSize      = 124
+	ddsPixelFormatSize = 32
+
+	ddsFlagCaps        = 0x1
+	ddsFlagHeight      = 0x2
+	ddsFlagWidth       = 0x4
+	ddsFlagPitch       = 0x8
+	ddsFlagPixelFormat = 0x1000
+	ddsFlagMipMapCount = 0x20000
+	ddsFlagLinearSize  = 0x80000
+
+	ddsPixelFlagAlphaPixels = 0x1
+	ddsPixelFlagFourCC      = 0x4
+	ddsPixelFlagRGB         = 0x40
+
+	ddsCapsComplex = 0x8
+	ddsCapsTexture = 0x1000
+	ddsCapsMipMap  = 0x400000
+
+	ddsCaps2CubemapAllFaces = 0xfe00
+
+	dx10ResourceDimensionTexture2D = 3
+	dx10MiscFlagTextureCube        = 0x4
+
+	cubeFaces = 6
+)
+
+type (
+	// DXGIFormat is the DXGI_FORMAT pixel format of an image
+	DXGIFormat uint32
+
+	dxgiFormatInfo struct {
+		name string
+		// blockBytes is the size of a 4x4 block for block-compressed
+		// formats or the size of a single pixel otherwise
+		blockBytes uint32
+		compressed bool
+		fourCC     string
+		legacyRGB  *ddsPixelFormat
+	}
+
+	ddsHeader struct {
+		Magic             [4]byte
+		Size              uint32
+		Flags             uint32
+		Height            uint32
+		Width             uint32
+		PitchOrLinearSize uint32
+		Depth             uint32
+		MipMapCount       uint32
+		Reserved1         [11]uint32
+		PixelFormat       ddsPixelFormat
+		Caps              uint32
+		Caps2             uint32
+		Caps3             uint32
+		Caps4             uint32
+		Reserved2         uint32
+	}
+
+	ddsHeaderDX10 struct {
+		DXGIFormat        DXGIFormat
+		ResourceDimension uint32
+		MiscFlag          uint32
+		ArraySize         uint32
+		MiscFlags2        uint32
+	}
+
+	ddsPixelFormat struct {
+		Size        uint32
+		Flags       uint32
+		FourCC      [4]byte
+		RGBBitCount uint32
+		RBitMask    uint32
+		GBitMask    uint32
+		BBitMask    uint32
+		ABitMask    uint32
+	}
+
+	// imageMeta contains the decoded image description of a HashFS v2
+	// image entry which is required to rebuild a DDS file from the
+	// headerless pixel data stored in the archive
+	imageMeta struct {
+		width, height  uint32
+		mipCount       uint32
+		format         DXGIFormat
+		isCube         bool
+		imageCount     uint32
+		pitchAlignment uint32
+		imageAlignment uint32
+	}
+
+	// mipData holds the packed pixel data of the mip levels first to
+	// first+count-1 of all images of a texture
+	mipData struct {
+		first, count uint32
+		data         []byte
+	}
+)
+
+//nolint:mnd // Pixel format masks
+var (
+	ddsPixelFormatBGRA = ddsPixelFormat{RGBBitCount: 32, RBitMask: 0xff0000, GBitMask: 0xff00, BBitMask: 0xff, ABitMask: 0xff000000}
+	ddsPixelFormatBGRX = ddsPixelFormat{RGBBitCount: 32, RBitMask: 0xff0000, GBitMask: 0xff00, BBitMask: 0xff}
+)
+
+//nolint:mnd // This is a lookup table of format sizes
+var dxgiFormats = map[DXGIFormat]dxgiFormatInfo{
+	2:   {name: "R32G32B32A32_FLOAT", blockBytes: 16},
+	10:  {name: "R16G16B16A16_FLOAT", blockBytes: 8},
+	11:  {name: "R16G16B16A16_UNORM", blockBytes: 8},
+	24:  {name: "R10G10B10A2_UNORM", blockBytes: 4},
+	26:  {name: "R11G11B10_FLOAT", blockBytes: 4},
+	28:  {name: "R8G8B8A8_UNORM", blockBytes: 4},
+	29:  {name: "R8G8B8A8_UNORM_SRGB", blockBytes: 4},
+	34:  {name: "R16G16_FLOAT", blockBytes: 4},
+	35:  {name: "R16G16_UNORM", blockBytes: 4},
+	41:  {name: "R32_FLOAT", blockBytes: 4},
+	49:  {name: "R8G8_UNORM", blockBytes: 2},
+	54:  {name: "R16_FLOAT", blockBytes: 2},
+	56:  {name: "R16_UNORM", blockBytes: 2},
+	61:  {name: "R8_UNORM", blockBytes: 1},
+	65:  {name: "A8_UNORM", blockBytes: 1},
+	71:  {name: "BC1_UNORM", blockBytes: 8, compressed: true, fourCC: "DXT1"},
+	72:  {name: "BC1_UNORM_SRGB", blockBytes: 8, compressed: true},
+	74:  {name: "BC2_UNORM", blockBytes: 16, compressed: true, fourCC: "DXT3"},
+	75:  {name: "BC2_UNORM_SRGB", blockBytes: 16, compressed: true},
+	77:  {name: "BC3_UNORM", blockBytes: 16, compressed: true, fourCC: "DXT5"},
+	78:  {name: "BC3_UNORM_SRGB", blockBytes: 16, compressed: true},
+	80:  {name: "BC4_UNORM", blockBytes: 8, compressed: true},
+	81:  {name: "BC4_SNORM", blockBytes: 8, compressed: true},
+	83:  {name: "BC5_UNORM", blockBytes: 16, compressed: true},
+	84:  {name: "BC5_SNORM", blockBytes: 16, compressed: true},
+	85:  {name: "B5G6R5_UNORM", blockBytes: 2},
+	86:  {name: "B5G5R5A1_UNORM", blockBytes: 2},
+	87:  {name: "B8G8R8A8_UNORM", blockBytes: 4, legacyRGB: &ddsPixelFormatBGRA},
+	88:  {name: "B8G8R8X8_UNORM", blockBytes: 4, legacyRGB: &ddsPixelFormatBGRX},
+	91:  {name: "B8G8R8A8_UNORM_SRGB", blockBytes: 4},
+	93:  {name: "B8G8R8X8_UNORM_SRGB", blockBytes: 4},
+	95:  {name: "BC6H_UF16", blockBytes: 16, compressed: true},
+	96:  {name: "BC6H_SF16", blockBytes: 16, compressed: true},
+	98:  {name: "BC7_UNORM", blockBytes: 16, compressed: true},
+	99:  {name: "BC7_UNORM_SRGB", blockBytes: 16, compressed: true},
+	115: {name: "B4G4R4A4_UNORM", blockBytes: 2},
+}
+
+// String returns the name of the format without DXGI_FORMAT_ prefix
+func (f DXGIFormat) String() string {
+	if info, ok := dxgiFormats[f]; ok {
+		return info.name
+	}
+	return fmt.Sprintf("DXGIFormat(%d)", uint32(f))
+}
+
+// newImageMeta decodes the image bitfields stored along with the
+// image entry. Width and height are stored decreased by one.
+//
+//nolint:mnd // Bitfield decoding
+func newImageMeta(width, height uint16, imgFlags uint32) *imageMeta {
+	return &imageMeta{
+		width:          uint32(width) + 1,
+		height:         uint32(height) + 1,
+		mipCount:       imgFlags&0xf + 1,
+		format:         DXGIFormat((imgFlags >> 4) & 0xff),
+		isCube:         (imgFlags>>12)&0x3 != 0,
+		imageCount:     (imgFlags>>14)&0x3f + 1,
+		pitchAlignment: 1 << ((imgFlags >> 20) & 0xf),
+		imageAlignment: 1 << ((imgFlags >> 24) & 0xf),
+	}
+}
+
+// canBuildDDS reports whether the format of the image is known well
+// enough to rebuild a DDS file from its pixel data
+func (i imageMeta) canBuildDDS() bool {
+	_, ok := dxgiFormats[i.format]
+	return ok
+}
+
+// ddsSize calculates the size of the DDS file built by buildDDS
+func (i imageMeta) ddsSize() uint32 {
+	size := uint32(len(ddsMagic) + ddsHeaderSize)
+	if i.needsDX10Header() {
+		size += uint32(binary.Size(ddsHeaderDX10{}))
+	}
+
+	i.eachSurface(func(_, pitch, rows uint32) {
+		size += pitch * rows
+	})
+
+	return size
+}
+
+// buildDDS takes the packed pixel data from the archive, strips the
+// pitch and image alignment and prefixes the data with a DDS header
+func (i imageMeta) buildDDS(packed []byte) ([]byte, error) {
+	return i.buildSplitDDS([]mipData{{first: 0, count: i.mipCount, data: packed}})
+}
+
+// buildSplitDDS builds the DDS file from pixel data split into
+// multiple chunks each containing a range of mip levels of all images
+func (i imageMeta) buildSplitDDS(parts []mipData) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.Grow(int(i.ddsSize()))
+
+	if err := i.writeDDSHeader(buf); err != nil {
+		return nil, fmt.Errorf("writing header: %w", err)
+	}
+
+	var (
+		offsets = make([]uint32, len(parts))
+		err     error
+	)
+	i.eachSurface(func(mip, pitch, rows uint32) {
+		if err != nil {
+			return
+		}
+
+		p := slices.IndexFunc(parts, func(p mipData) bool { return mip >= p.first && mip < p.first+p.count })
+		if p < 0 {
+			err = fmt.Errorf("no pixel data for mip %d", mip)
+			return
+		}
+
+		alignedPitch := alignUp(pitch, i.pitchAlignment)
+		for y := uint32(0); y < rows; y++ {
+			start := offsets[p] + y*alignedPitch
+			if start+pitch > uint32(len(parts[p].data)) { //#nosec:G115 // Texture data will never exceed 4GB
+				err = fmt.Errorf("pixel data too short: %w", io.ErrUnexpectedEOF)
+				return
+			}
+			buf.Write(parts[p].data[start : start+pitch])
+		}
+
+		offsets[p] += alignUp(alignedPitch*rows, i.imageAlignment)
+	})
+
+	return buf.Bytes(), err
+}
+
+// eachSurface calls fn for every mip-level of every image contained
+// in the texture in the order they are stored within the DDS file
+func (i imageMeta) eachSurface(fn func(mip, pitch, rows uint32)) {
+	info := dxgiFormats[i.format]
+
+	for img := uint32(0); img < i.imageCount; img++ {
+		w, h := i.width, i.height
+		for mip := uint32(0); mip < i.mipCount; mip++ {
+			if info.compressed {
+				fn(mip, max(1, (w+3)/4)*info.blockBytes, max(1, (h+3)/4)) //nolint:mnd // Block size is 4x4 pixels
+			} else {
+				fn(mip, w*info.blockBytes, h)
+			}
+
+			w, h = max(1, w/2), max(1, h/2) //nolint:mnd
+		}
+	}
+}
+
+func (i imageMeta) needsDX10Header() bool {
+	info := dxgiFormats[i.format]
+	return (info.fourCC == "" && info.legacyRGB == nil) || (i.isCube && i.imageCount != cubeFaces) || (!i.isCube && i.imageCount > 1)
+}
+
+func (i imageMeta) writeDDSHeader(w io.Writer) error {
+	info := dxgiFormats[i.format]
+
+	hdr := ddsHeader{
+		Size:        ddsHeaderSize,
+		Flags:       ddsFlagCaps | ddsFlagHeight | ddsFlagWidth | ddsFlagPixelFormat,
+		Height:      i.height,
+		Width:       i.width,
+		MipMapCount: i.mipCount,
+		PixelFormat: ddsPixelFormat{Size: ddsPixelFormatSize},
+		Caps:        ddsCapsTexture,
+	}
+	copy(hdr.Magic[:], ddsMagic)
+
+	if info.compressed {
+		hdr.Flags |= ddsFlagLinearSize
+		hdr.PitchOrLinearSize = max(1, (i.width+3)/4) * max(1, (i.height+3)/4) * info.blockBytes //nolint:mnd // Block size is 4x4 pixels
+	} else {
+		hdr.Flags |= ddsFlagPitch
+		hdr.PitchOrLinearSize = i.width * info.blockBytes
+	}
+
+	if i.mipCount > 1 {
+		hdr.Flags |= ddsFlagMipMapCount
+		hdr.Caps |= ddsCapsComplex | ddsCapsMipMap
+	}
+
+	if i.isCube {
+		hdr.Caps |= ddsCapsComplex
+		hdr.Caps2 |= ddsCaps2CubemapAllFaces
+	}
+
+	dx10 := i.needsDX10Header()
+	switch {
+	case dx10:
+		hdr.PixelFormat.Flags = ddsPixelFlagFourCC
+		copy(hdr.PixelFormat.FourCC[:], "DX10")
+
+	case info.fourCC != "":
+		hdr.PixelFormat.Flags = ddsPixelFlagFourCC
+		copy(hdr.PixelFormat.FourCC[:], info.fourCC)
+
+	default:
+		hdr.PixelFormat = *info.legacyRGB
+		hdr.PixelFormat.Size = ddsPixelFormatSize
+		hdr.PixelFormat.Flags = ddsPixelFlagRGB
+		if hdr.PixelFormat.ABitMask != 0 {
+			hdr.PixelFormat.Flags |= ddsPixelFlagAlphaPixels
+		}
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, hdr); err != nil {
+		return fmt.Errorf("writing DDS header: %w", err)
+	}
+
+	if !dx10 {
+		return nil
+	}
+
+	ext := ddsHeaderDX10{
+		DXGIFormat:        i.format,
+		ResourceDimension: dx10ResourceDimensionTexture2D,
+		ArraySize:         i.imageCount,
+	}
+
+	if i.isCube {
+		ext.MiscFlag = dx10MiscFlagTextureCube
+		ext.ArraySize = max(1, i.imageCount/cubeFaces)
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, ext); err != nil {
+		return fmt.Errorf("writing DX10 header: %w", err)
+	}
+
+	return nil
+}
+
+func alignUp(v, alignment uint32) uint32 {
+	if alignment <= 1 {
+		return v
+	}
+	return (v + alignment - 1) / alignment * alignment
+}
@@ -0,0 +1,80 @@
+package scs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestBuildDDS(t *testing.T) {
+	// 8x8 BC1 texture with 2 mips, 32 byte pitch- and 512 byte image-alignment
+	img := newImageMeta(7, 7, 1|71<<4|5<<20|9<<24)
+
+	if img.width != 8 || img.height != 8 || img.mipCount != 2 || img.imageCount != 1 {
+		t.Fatalf("unexpected image meta: %+v", img)
+	}
+
+	packed := make([]byte, 512+32)
+	copy(packed[0:], bytes.Repeat([]byte{0x1}, 16))  // mip 0, row 0
+	copy(packed[32:], bytes.Repeat([]byte{0x2}, 16)) // mip 0, row 1
+	copy(packed[512:], bytes.Repeat([]byte{0x3}, 8)) // mip 1, row 0
+
+	dds, err := img.buildDDS(packed)
+	if err != nil {
+		t.Fatalf("building DDS: %s", err)
+	}
+
+	if uint32(len(dds)) != img.ddsSize() {
+		t.Errorf("unexpected DDS size: expect=%d result=%d", img.ddsSize(), len(dds))
+	}
+
+	var hdr ddsHeader
+	if err = binary.Read(bytes.NewReader(dds), binary.LittleEndian, &hdr); err != nil {
+		t.Fatalf("reading DDS header: %s", err)
+	}
+
+	if string(hdr.Magic[:]) != "DDS " || string(hdr.PixelFormat.FourCC[:]) != "DXT1" {
+		t.Errorf("unexpected header: magic=%q fourcc=%q", hdr.Magic, hdr.PixelFormat.FourCC)
+	}
+
+	if hdr.Width != 8 || hdr.Height != 8 || hdr.MipMapCount != 2 {
+		t.Errorf("unexpected dimensions: %dx%d, %d mips", hdr.Width, hdr.Height, hdr.MipMapCount)
+	}
+
+	expect := append(append(bytes.Repeat([]byte{0x1}, 16), bytes.Repeat([]byte{0x2}, 16)...), bytes.Repeat([]byte{0x3}, 8)...)
+	if !bytes.Equal(dds[len(ddsMagic)+ddsHeaderSize:], expect) {
+		t.Errorf("unexpected pixel data: %x", dds[len(ddsMagic)+ddsHeaderSize:])
+	}
+}
+
+func TestBuildDDSNeedsDX10(t *testing.T) {
+	// 4x4 BC7 texture requires the DX10 extension header
+	img := newImageMeta(3, 3, 98<<4)
+
+	dds, err := img.buildDDS(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("building DDS: %s", err)
+	}
+
+	var (
+		hdr  ddsHeader
+		dx10 ddsHeaderDX10
+		r    = bytes.NewReader(dds)
+	)
+
+	if err = binary.Read(r, binary.LittleEndian, &hdr); err != nil {
+		t.Fatalf("reading DDS header: %s", err)
+	}
+
+	if err = binary.Read(r, binary.LittleEndian, &dx10); err != nil {
+		t.Fatalf("reading DX10 header: %s", err)
+	}
+
+	if string(hdr.PixelFormat.FourCC[:]) != "DX10" || dx10.DXGIFormat != 98 || dx10.ArraySize != 1 {
+		t.Errorf("unexpected headers: fourcc=%q %+v", hdr.PixelFormat.FourCC, dx10)
+	}
+
+	if r.Len() != 16 {
+		t.Errorf("unexpected pixel data length: %d", r.Len())
+	}
+}
@@ -0,0 +1,108 @@
+package scs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/Luzifer/scs-extract/b0rkhash"
+)
+
+// NewDirReader creates a Reader serving the files of a plain directory
+// (for example an unpacked mod) through the same interface as the
+// archive readers
+func NewDirReader(dir string) (*Reader, error) {
+	r := &Reader{isDir: true}
+	r.initVirtualRoot()
+
+	if err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return fmt.Errorf("getting relative path: %w", err)
+		}
+
+		name := filepath.ToSlash(rel)
+		if name == "." {
+			return nil
+		}
+
+		if d.IsDir() {
+			r.virtualDir(name)
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			// Skip symlinks, devices, ...
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("getting file info: %w", err)
+		}
+
+		r.addVirtualFile(&File{
+			Name: name,
+			Hash: b0rkhash.CityHash64([]byte(name)),
+			Size: clampUint32(uint64(info.Size())), //#nosec:G115 // Sizes are never negative
+			open: func() (io.ReadCloser, error) {
+				return os.Open(p) //#nosec:G304 // Intended to open files within the directory
+			},
+		})
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("walking directory: %w", err)
+	}
+
+	return r, nil
+}
+
+// addVirtualFile registers a file for readers not having directory
+// listings (ZIP archives, plain directories) and creates all missing
+// parent directories
+func (r *Reader) addVirtualFile(f *File) {
+	parent := r.virtualDir(path.Dir(f.Name))
+	parent.children = append(parent.children, path.Base(f.Name))
+	r.byName[f.Name] = f
+	r.Files = append(r.Files, f)
+}
+
+func (r *Reader) initVirtualRoot() {
+	r.root = &File{Hash: b0rkhash.CityHash64(nil), IsDirectory: true, isRoot: true}
+	r.byName = map[string]*File{"": r.root}
+	r.Files = append(r.Files, r.root)
+}
+
+// virtualDir returns the directory entry for the given name and
+// creates it including all of its parents in case it does not exist
+// as ZIP files and plain directories do not have directory listings
+func (r *Reader) virtualDir(name string) *File {
+	if name == "." {
+		name = ""
+	}
+
+	if d, ok := r.byName[name]; ok {
+		return d
+	}
+
+	d := &File{
+		Name:        name,
+		Hash:        b0rkhash.CityHash64([]byte(name)),
+		IsDirectory: true,
+	}
+
+	parent := r.virtualDir(path.Dir(name))
+	parent.children = append(parent.children, path.Base(name))
+	r.byName[name] = d
+	r.Files = append(r.Files, d)
+
+	return d
+}
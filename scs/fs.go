@@ -0,0 +1,217 @@
+package scs
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+const (
+	dirFileMode  fs.FileMode = fs.ModeDir | 0o555
+	fileFileMode fs.FileMode = 0o444
+)
+
+type (
+	fileInfo struct {
+		f *File
+	}
+
+	openDir struct {
+		fileInfo
+		entries []fs.DirEntry
+		pos     int
+	}
+
+	openFile struct {
+		fileInfo
+		io.ReadCloser
+	}
+)
+
+var (
+	_ fs.FS         = (*Reader)(nil)
+	_ fs.ReadDirFS  = (*Reader)(nil)
+	_ fs.ReadFileFS = (*Reader)(nil)
+	_ fs.StatFS     = (*Reader)(nil)
+
+	_ fs.DirEntry    = fileInfo{}
+	_ fs.FileInfo    = fileInfo{}
+	_ fs.ReadDirFile = (*openDir)(nil)
+)
+
+// DirEntry returns the fs.DirEntry representation of the file
+func (f *File) DirEntry() fs.DirEntry { return fileInfo{f} }
+
+// FileInfo returns the fs.FileInfo representation of the file
+func (f *File) FileInfo() fs.FileInfo { return fileInfo{f} }
+
+// Open implements fs.FS and opens the named file or directory
+// for reading
+func (r *Reader) Open(name string) (fs.File, error) {
+	f, err := r.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.IsDirectory {
+		entries, err := r.readDir(f)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &openDir{fileInfo: fileInfo{f}, entries: entries}, nil
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return &openFile{fileInfo: fileInfo{f}, ReadCloser: rc}, nil
+}
+
+// ReadDir implements fs.ReadDirFS and returns the entries of the
+// named directory sorted by filename
+func (r *Reader) ReadDir(name string) ([]fs.DirEntry, error) {
+	f, err := r.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !f.IsDirectory {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	entries, err := r.readDir(f)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	return entries, nil
+}
+
+// ReadFile implements fs.ReadFileFS and returns the whole
+// (decompressed) content of the named file
+func (r *Reader) ReadFile(name string) ([]byte, error) {
+	f, err := r.lookup("read", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.IsDirectory {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	defer rc.Close() //nolint:errcheck
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+
+	return data, nil
+}
+
+// Stat implements fs.StatFS and returns the fs.FileInfo for the
+// named file or directory
+func (r *Reader) Stat(name string) (fs.FileInfo, error) {
+	f, err := r.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.FileInfo(), nil
+}
+
+func (r *Reader) lookup(op, name string) (*File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	if name == "." {
+		name = ""
+	}
+
+	f, ok := r.byName[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+
+	return f, nil
+}
+
+func (r *Reader) readDir(dir *File) ([]fs.DirEntry, error) {
+	entries := make([]fs.DirEntry, 0, len(dir.children))
+	for _, child := range dir.children {
+		f, ok := r.byName[path.Join(dir.Name, child)]
+		if !ok {
+			return nil, fs.ErrNotExist
+		}
+		entries = append(entries, f.DirEntry())
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	return entries, nil
+}
+
+// --- fileInfo
+
+func (fi fileInfo) Info() (fs.FileInfo, error) { return fi, nil }
+func (fi fileInfo) IsDir() bool                { return fi.f.IsDirectory }
+func (fi fileInfo) ModTime() time.Time         { return time.Time{} }
+func (fi fileInfo) Size() int64                { return int64(fi.f.Size) }
+func (fi fileInfo) Sys() any                   { return fi.f }
+func (fi fileInfo) Type() fs.FileMode          { return fi.Mode().Type() }
+
+func (fi fileInfo) Mode() fs.FileMode {
+	if fi.f.IsDirectory {
+		return dirFileMode
+	}
+	return fileFileMode
+}
+
+func (fi fileInfo) Name() string {
+	if fi.f.Name == "" {
+		return "."
+	}
+	return path.Base(fi.f.Name)
+}
+
+// --- openDir
+
+func (*openDir) Close() error { return nil }
+
+func (d *openDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.f.Name, Err: fs.ErrInvalid}
+}
+
+func (d *openDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remain := d.entries[d.pos:]
+	if n <= 0 {
+		d.pos = len(d.entries)
+		return remain, nil
+	}
+
+	if len(remain) == 0 {
+		return nil, io.EOF
+	}
+
+	if n > len(remain) {
+		n = len(remain)
+	}
+	d.pos += n
+
+	return remain[:n], nil
+}
+
+func (d *openDir) Stat() (fs.FileInfo, error) { return d.fileInfo, nil }
+
+// --- openFile
+
+func (f *openFile) Stat() (fs.FileInfo, error) { return f.fileInfo, nil }
@@ -0,0 +1,79 @@
+package scs
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReaderFS(t *testing.T) {
+	r, err := NewReader(bytes.NewReader(writeTestArchive(t, map[string]string{
+		"manifest.sii":       "SiiNunit { }",
+		"def/city.sii":       "city_data: .berlin",
+		"def/country/de.sii": "short",
+	})))
+	if err != nil {
+		t.Fatalf("reading archive: %s", err)
+	}
+
+	if f := r.Format(); f != "HashFS v2" {
+		t.Errorf("unexpected format %q", f)
+	}
+
+	if err = fstest.TestFS(r, "manifest.sii", "def/city.sii", "def/country/de.sii"); err != nil {
+		t.Error(err)
+	}
+
+	// The root directory is reachable as "."
+	info, err := r.Stat(".")
+	if err != nil {
+		t.Fatalf("stat root: %s", err)
+	}
+	if !info.IsDir() {
+		t.Error("root is not a directory")
+	}
+
+	entries, err := r.ReadDir(".")
+	if err != nil {
+		t.Fatalf("reading root: %s", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if len(names) != 2 || names[0] != "def" || names[1] != "manifest.sii" {
+		t.Errorf("unexpected root entries %v", names)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		call   func() error
+		expect error
+	}{
+		{"open missing", func() error { _, err := r.Open("def/missing.sii"); return err }, fs.ErrNotExist},
+		{"stat missing", func() error { _, err := r.Stat("missing"); return err }, fs.ErrNotExist},
+		{"read missing", func() error { _, err := r.ReadFile("def/country/fr.sii"); return err }, fs.ErrNotExist},
+		{"readdir file", func() error { _, err := r.ReadDir("manifest.sii"); return err }, fs.ErrInvalid},
+		{"read directory", func() error { _, err := r.ReadFile("def"); return err }, fs.ErrInvalid},
+		{"invalid path", func() error { _, err := r.Open("/def"); return err }, fs.ErrInvalid},
+	} {
+		if err := tc.call(); !errors.Is(err, tc.expect) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expect, err)
+		}
+	}
+
+	sub, err := fs.Sub(r, "def")
+	if err != nil {
+		t.Fatalf("creating sub FS: %s", err)
+	}
+
+	data, err := fs.ReadFile(sub, "country/de.sii")
+	if err != nil {
+		t.Fatalf("reading from sub FS: %s", err)
+	}
+	if string(data) != "short" {
+		t.Errorf("unexpected content %q", data)
+	}
+}
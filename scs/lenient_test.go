@@ -0,0 +1,138 @@
+package scs
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// rewriteMetadata replaces the metadata table of the archive with the
+// modified version appended to the end of the archive
+func rewriteMetadata(t *testing.T, data []byte, modify func(words []uint32)) []byte {
+	t.Helper()
+
+	var hdr fileHeader
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &hdr); err != nil {
+		t.Fatalf("reading header: %s", err)
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(data[hdr.MetadataTableStart : hdr.MetadataTableStart+uint64(hdr.MetadataTableLength)]))
+	if err != nil {
+		t.Fatalf("opening metadata table: %s", err)
+	}
+
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("reading metadata table: %s", err)
+	}
+
+	words := make([]uint32, len(raw)/4)
+	if err = binary.Read(bytes.NewReader(raw), binary.LittleEndian, words); err != nil {
+		t.Fatalf("decoding metadata table: %s", err)
+	}
+
+	modify(words)
+
+	buf := new(bytes.Buffer)
+	if err = binary.Write(buf, binary.LittleEndian, words); err != nil {
+		t.Fatalf("encoding metadata table: %s", err)
+	}
+
+	table, err := zlibCompress(buf.Bytes())
+	if err != nil {
+		t.Fatalf("compressing metadata table: %s", err)
+	}
+
+	hdr.MetadataTableStart = uint64(len(data))
+	hdr.MetadataTableLength = uint32(len(table))
+
+	out := bytes.NewBuffer(nil)
+	if err = binary.Write(out, binary.LittleEndian, hdr); err != nil {
+		t.Fatalf("encoding header: %s", err)
+	}
+	out.Write(data[out.Len():])
+	out.Write(table)
+
+	return out.Bytes()
+}
+
+func TestLenientUnknownMetadata(t *testing.T) {
+	data := writeTestArchive(t, map[string]string{
+		"def/city.sii":    "city",
+		"def/company.sii": "company",
+	})
+
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("reading archive: %s", err)
+	}
+
+	company, err := r.lookup("test", "def/company.sii")
+	if err != nil {
+		t.Fatalf("looking up file: %s", err)
+	}
+
+	// Change the type of the company entry to something unknown
+	data = rewriteMetadata(t, data, func(words []uint32) {
+		for i := 0; i+metaChunkWords < len(words); i += metaEntryHeaderSize + metaEntryFileSize {
+			if uint64(words[i+4])*offsetBlockSize == company.offset {
+				words[i] = metaHeader{Index: words[i] & metaHeaderIndexMask, Type: 200}.word()
+			}
+		}
+	})
+
+	var metaErr *MetadataError
+	if _, err = NewReader(bytes.NewReader(data)); !errors.As(err, &metaErr) {
+		t.Fatalf("expected MetadataError in strict mode, got %v", err)
+	}
+
+	if r, err = NewReader(bytes.NewReader(data), Lenient()); err != nil {
+		t.Fatalf("reading archive in lenient mode: %s", err)
+	}
+
+	if content, err := r.ReadFile("def/city.sii"); err != nil || string(content) != "city" {
+		t.Errorf("reading intact file: %q, %v", content, err)
+	}
+
+	if _, err = r.Stat("def/company.sii"); err == nil {
+		t.Error("expected undecodable file to be skipped")
+	}
+
+	if len(r.Warnings) != 2 { //nolint:mnd
+		t.Fatalf("expected 2 warnings, got %v", r.Warnings)
+	}
+
+	if !errors.As(r.Warnings[0], &metaErr) || metaErr.Hash != company.Hash || len(metaErr.Raw) != 1 {
+		t.Fatalf("unexpected first warning: %v", r.Warnings[0])
+	}
+
+	if metaErr.Raw[0].Type != 200 || len(metaErr.Raw[0].Data) != metaChunkWords*4 {
+		t.Errorf("unexpected raw metadata: %+v", metaErr.Raw[0])
+	}
+
+	if !strings.Contains(r.Warnings[1].Error(), "reference to void: def/company.sii") {
+		t.Errorf("unexpected second warning: %v", r.Warnings[1])
+	}
+}
+
+func TestLenientCyclicDirectoryListing(t *testing.T) {
+	for version, data := range cyclicArchives(t) {
+		r, err := NewReader(bytes.NewReader(data), Lenient())
+		if err != nil {
+			t.Fatalf("%s: reading archive in lenient mode: %s", version, err)
+		}
+
+		if len(r.Warnings) != 1 || !strings.Contains(r.Warnings[0].Error(), "cyclic directory listing") {
+			t.Errorf("%s: unexpected warnings %v", version, r.Warnings)
+		}
+
+		// The directory itself is still accessible
+		if _, err = r.Stat("def"); err != nil {
+			t.Errorf("%s: stat def: %s", version, err)
+		}
+	}
+}
@@ -1,89 +1,174 @@
 package scs
 
-const offsetBlockSize = 16 // byte
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+const (
+	offsetBlockSize = 16 // byte
+
+	// The metadata table is a list of uint32 words. Each catalog entry
+	// references MetadataCount header words, each header holds the
+	// type in its upper byte and the index of its payload words.
+	metaHeaderIndexMask = 0x00ffffff
+	metaHeaderTypeShift = 24
+
+	// Data chunks store a 28 bit compressed size and 4 flag bits
+	// sharing the same word
+	metaChunkSizeMask  = 0x0fffffff
+	metaChunkFlagShift = 28
+	metaFlagCompressed = 0x1
+
+	metaChunkWords    = 4
+	metaImageWords    = 2
+	metaMipProxyWords = 2
+	metaSampleWords   = 1
+)
 
 type (
-	iMetaEntry interface {
-		Fill(*catalogMetaEntry)
+	// MetadataError describes a catalog entry whose metadata could not
+	// be (fully) decoded. Metadata of unknown types is attached as raw
+	// words to be inspected.
+	MetadataError struct {
+		Hash uint64
+		Raw  []RawMetadata
+		Err  error
 	}
 
-	metaEntry struct {
-		t metaEntryType
-		p iMetaEntry
+	// RawMetadata contains the payload of a metadata entry of a type
+	// not handled by the parser
+	RawMetadata struct {
+		Type uint8
+		Data []byte
 	}
 
-	metaEntryBrokenOctal      [3]byte
-	metaEntryBrokenOctalImage [4]byte
-
-	metaEntryType struct {
-		Index metaEntryBrokenOctal
+	metaHeader struct {
+		Index uint32
 		Type  catalogMetaEntryType
 	}
 
-	metaEntryDir struct {
-		CompressedSize metaEntryBrokenOctal
+	// metaChunk describes the location of a data block within the
+	// archive (the content of plain files, directory listings and
+	// image data)
+	metaChunk struct {
+		CompressedSize uint32
 		Flags          byte
 		Size           uint32
-		Unknown2       uint32
-		OffsetBlock    uint32
+		Unknown        uint32
+		Offset         uint64
 	}
 
-	metaEntryFile struct {
-		CompressedSize metaEntryBrokenOctal
-		Flags          byte
-		Size           uint32
-		Unknown2       uint32
-		OffsetBlock    uint32
+	// metaTable holds the decoded metadata table words
+	metaTable struct {
+		words []uint32
+		// starts contains the sorted word indices where a header block
+		// or a payload starts to determine the size of unknown payloads
+		starts []uint32
 	}
+)
+
+func (e *MetadataError) Error() string {
+	return fmt.Sprintf("entry %016x: %s", e.Hash, e.Err)
+}
 
-	metaEntryImage struct {
-		Unknown1       uint64
-		TextureWidth   uint16
-		TextureHeight  uint16
-		ImgFlags       uint32
-		SampleFlags    uint32
-		CompressedSize metaEntryBrokenOctalImage
-		Unknown3       [8]byte
-		OffsetBlock    uint32
+func (e *MetadataError) Unwrap() error { return e.Err }
+
+func newMetaTable(words []uint32, entries []catalogEntry) *metaTable {
+	t := &metaTable{words: words}
+
+	seen := make(map[uint32]bool)
+	add := func(i uint32) {
+		if !seen[i] {
+			seen[i] = true
+			t.starts = append(t.starts, i)
+		}
 	}
-)
 
-func (m metaEntry) Fill(c *catalogMetaEntry) {
-	c.Index = m.t.Index.Uint32()
-	m.p.Fill(c)
+	for _, e := range entries {
+		add(e.MetadataIndex)
+		for _, h := range t.headers(e) {
+			add(h.Index)
+		}
+	}
+	add(uint32(len(words))) //#nosec:G115 // Table size is limited by the uint32 header field
+
+	sort.Slice(t.starts, func(i, j int) bool { return t.starts[i] < t.starts[j] })
+	return t
 }
 
-func (m metaEntryDir) Fill(c *catalogMetaEntry) {
-	c.IsDirectory = true
+// chunk decodes the data chunk stored at the given word index
+func (t *metaTable) chunk(idx uint32) (metaChunk, error) {
+	w, err := t.payload(idx, metaChunkWords)
+	if err != nil {
+		return metaChunk{}, err
+	}
 
-	c.Offset = uint64(m.OffsetBlock) * offsetBlockSize
-	c.CompressedSize = m.CompressedSize.Uint32()
-	c.Size = m.Size
-	c.Flags = m.Flags
+	return metaChunk{
+		CompressedSize: w[0] & metaChunkSizeMask,
+		Flags:          byte(w[0] >> metaChunkFlagShift),
+		Size:           w[1],
+		Unknown:        w[2],
+		Offset:         uint64(w[3]) * offsetBlockSize,
+	}, nil
 }
 
-func (m metaEntryFile) Fill(c *catalogMetaEntry) {
-	c.Offset = uint64(m.OffsetBlock) * offsetBlockSize
-	c.CompressedSize = m.CompressedSize.Uint32()
-	c.Size = m.Size
-	c.Flags = m.Flags
+// headers returns the metadata headers referenced by the catalog entry
+func (t *metaTable) headers(e catalogEntry) []metaHeader {
+	var headers []metaHeader
+	for i := uint32(0); i < uint32(e.MetadataCount); i++ {
+		idx := e.MetadataIndex + i
+		if idx >= uint32(len(t.words)) { //#nosec:G115 // Table size is limited by the uint32 header field
+			break
+		}
+
+		headers = append(headers, metaHeader{
+			Index: t.words[idx] & metaHeaderIndexMask,
+			Type:  catalogMetaEntryType(t.words[idx] >> metaHeaderTypeShift),
+		})
+	}
+
+	return headers
 }
 
-func (m metaEntryImage) Fill(c *catalogMetaEntry) {
-	c.Offset = uint64(m.OffsetBlock) * offsetBlockSize
-	c.CompressedSize = m.CompressedSize.Uint32()
-	c.Size = m.CompressedSize.Uint32()
-	c.IsCompressed = m.CompressedSize.IsCompressed()
+// payload returns count words starting at the given index
+func (t *metaTable) payload(idx, count uint32) ([]uint32, error) {
+	if uint64(idx)+uint64(count) > uint64(len(t.words)) {
+		return nil, fmt.Errorf("payload at word %d exceeds metadata table", idx)
+	}
+
+	return t.words[idx : idx+count], nil
 }
 
-func (m metaEntryBrokenOctal) Uint32() uint32 {
-	return uint32(m[0]) + uint32(m[1])<<8 + uint32(m[2])<<16
+// raw returns the payload of an unknown metadata type which is
+// assumed to reach up to the next known header block or payload
+func (t *metaTable) raw(h metaHeader) RawMetadata {
+	end := uint32(len(t.words)) //#nosec:G115 // Table size is limited by the uint32 header field
+	if i := sort.Search(len(t.starts), func(i int) bool { return t.starts[i] > h.Index }); i < len(t.starts) {
+		end = t.starts[i]
+	}
+
+	raw := RawMetadata{Type: uint8(h.Type)}
+	if h.Index < end {
+		raw.Data = make([]byte, 0, (end-h.Index)*4) //nolint:mnd
+		for _, w := range t.words[h.Index:end] {
+			raw.Data = binary.LittleEndian.AppendUint32(raw.Data, w)
+		}
+	}
+
+	return raw
 }
 
-func (m metaEntryBrokenOctalImage) IsCompressed() bool {
-	return (m[3] & 0xf0) != 0 //nolint:mnd
+func (h metaHeader) word() uint32 {
+	return h.Index&metaHeaderIndexMask | uint32(h.Type)<<metaHeaderTypeShift
 }
 
-func (m metaEntryBrokenOctalImage) Uint32() uint32 {
-	return uint32(m[0]) + uint32(m[1])<<8 + uint32(m[2])<<16 + uint32(m[3])<<24
+func (c metaChunk) words() []uint32 {
+	return []uint32{
+		c.CompressedSize&metaChunkSizeMask | uint32(c.Flags)<<metaChunkFlagShift,
+		c.Size,
+		c.Unknown,
+		uint32(c.Offset / offsetBlockSize), //#nosec:G115 // Offset blocks allow 64GB archives
+	}
 }
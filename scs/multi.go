@@ -0,0 +1,286 @@
+package scs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Priorities used by GameArchivePriority to sort the archives of a
+// game installation into the order the game mounts them
+const (
+	PriorityBase = iota * 10 //nolint:mnd
+	PriorityCore
+	PriorityDef
+	PriorityEffect
+	PriorityLocale
+	PriorityOther
+	PriorityDLC
+	PriorityMod
+)
+
+type (
+	// Layer is a single source mounted into a MultiReader
+	Layer struct {
+		Name     string
+		Priority int
+		Reader   *Reader
+	}
+
+	// MultiReader combines multiple archives (and plain directories)
+	// into one layered view: files in layers with higher priority
+	// override files with the same name in lower layers. Layers with
+	// the same priority are applied in the order they were added.
+	MultiReader struct {
+		layers  []*Layer
+		closers []io.Closer
+	}
+)
+
+var (
+	_ fs.FS         = (*MultiReader)(nil)
+	_ fs.ReadDirFS  = (*MultiReader)(nil)
+	_ fs.ReadFileFS = (*MultiReader)(nil)
+	_ fs.StatFS     = (*MultiReader)(nil)
+)
+
+// GameArchivePriority returns the priority of the named archive when
+// mounting the archives found in the installation directory of the
+// game: base archives first, followed by core, def, effect and locale,
+// other archives and the DLCs last
+func GameArchivePriority(name string) int {
+	name = strings.ToLower(strings.TrimSuffix(filepath.Base(name), filepath.Ext(name)))
+
+	switch {
+	case strings.HasPrefix(name, "base"):
+		return PriorityBase
+	case name == "core":
+		return PriorityCore
+	case name == "def":
+		return PriorityDef
+	case name == "effect":
+		return PriorityEffect
+	case name == "locale":
+		return PriorityLocale
+	case strings.HasPrefix(name, "dlc_"):
+		return PriorityDLC
+	default:
+		return PriorityOther
+	}
+}
+
+// NewMultiReader creates an empty MultiReader
+func NewMultiReader() *MultiReader {
+	return &MultiReader{}
+}
+
+// OpenGameDir opens all .scs archives within the given game
+// installation directory in the order the game mounts them. The
+// options are passed to the Reader of every archive.
+func OpenGameDir(dir string, opts ...ReaderOption) (*MultiReader, error) {
+	archives, err := filepath.Glob(filepath.Join(dir, "*.scs"))
+	if err != nil {
+		return nil, fmt.Errorf("listing archives: %w", err)
+	}
+
+	if len(archives) == 0 {
+		return nil, fmt.Errorf("no archives found in %s", dir)
+	}
+
+	m := NewMultiReader()
+	for _, archive := range archives {
+		if err = m.AddArchive(archive, GameArchivePriority(archive), opts...); err != nil {
+			m.Close() //nolint:errcheck,gosec // Already in error state
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Add mounts an already opened Reader under the given name
+func (m *MultiReader) Add(name string, r *Reader, priority int) {
+	m.layers = append(m.layers, &Layer{Name: name, Priority: priority, Reader: r})
+	sort.SliceStable(m.layers, func(i, j int) bool { return m.layers[i].Priority < m.layers[j].Priority })
+}
+
+// AddArchive opens the archive at the given path and mounts it. The
+// file is kept open until Close is called.
+func (m *MultiReader) AddArchive(archive string, priority int, opts ...ReaderOption) error {
+	f, err := os.Open(archive) //#nosec:G304 // Intended to open arbitrary files
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", archive, err)
+	}
+
+	r, err := NewReader(f, opts...)
+	if err != nil {
+		f.Close() //nolint:errcheck,gosec // Already in error state
+		return fmt.Errorf("reading %s: %w", archive, err)
+	}
+
+	m.closers = append(m.closers, f)
+	m.Add(archive, r, priority)
+	return nil
+}
+
+// AddDir mounts a plain directory
+func (m *MultiReader) AddDir(dir string, priority int) error {
+	r, err := NewDirReader(dir)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", dir, err)
+	}
+
+	m.Add(dir, r, priority)
+	return nil
+}
+
+// Close closes all archives opened by AddArchive
+func (m *MultiReader) Close() error {
+	var errs []error
+	for _, c := range m.closers {
+		errs = append(errs, c.Close())
+	}
+	m.closers = nil
+
+	return errors.Join(errs...)
+}
+
+// Files returns the effective files of all layers sorted by name
+func (m *MultiReader) Files() []*File {
+	seen := make(map[string]bool)
+	var files []*File
+
+	for i := len(m.layers) - 1; i >= 0; i-- {
+		for _, f := range m.layers[i].Reader.Files {
+			if seen[f.Name] || (f.Name == "" && !f.IsDirectory) {
+				continue
+			}
+			seen[f.Name] = true
+			files = append(files, f)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files
+}
+
+// Layers returns the mounted layers ordered by ascending priority
+func (m *MultiReader) Layers() []*Layer {
+	return m.layers
+}
+
+// Open implements fs.FS and opens the effective file or the merged
+// directory with the given name
+func (m *MultiReader) Open(name string) (fs.File, error) {
+	_, f, err := m.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !f.IsDirectory {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &openFile{fileInfo: fileInfo{f}, ReadCloser: rc}, nil
+	}
+
+	entries, err := m.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &openDir{fileInfo: fileInfo{f}, entries: entries}, nil
+}
+
+// Provider returns the layer providing the effective version of the
+// named file together with the file itself
+func (m *MultiReader) Provider(name string) (*Layer, *File, error) {
+	return m.lookup("provider", name)
+}
+
+// ReadDir implements fs.ReadDirFS and returns the merged entries of
+// the named directory from all layers
+func (m *MultiReader) ReadDir(name string) ([]fs.DirEntry, error) {
+	if _, _, err := m.lookup("readdir", name); err != nil {
+		return nil, err
+	}
+
+	var (
+		dir     = strings.TrimPrefix(path.Clean("/"+name), "/")
+		entries []fs.DirEntry
+		found   bool
+		seen    = make(map[string]bool)
+	)
+
+	for _, l := range m.layers {
+		d, err := l.Reader.lookup("readdir", name)
+		if err != nil || !d.IsDirectory {
+			continue
+		}
+		found = true
+
+		for _, child := range d.children {
+			if seen[child] {
+				continue
+			}
+			seen[child] = true
+
+			_, f, err := m.lookup("readdir", path.Join(dir, child))
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, f.DirEntry())
+		}
+	}
+
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// ReadFile implements fs.ReadFileFS and returns the content of the
+// effective file with the given name
+func (m *MultiReader) ReadFile(name string) ([]byte, error) {
+	l, _, err := m.lookup("read", name)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.Reader.ReadFile(name)
+}
+
+// Stat implements fs.StatFS and returns the fs.FileInfo for the
+// effective file with the given name
+func (m *MultiReader) Stat(name string) (fs.FileInfo, error) {
+	_, f, err := m.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.FileInfo(), nil
+}
+
+func (m *MultiReader) lookup(op, name string) (*Layer, *File, error) {
+	if !fs.ValidPath(name) {
+		return nil, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	for i := len(m.layers) - 1; i >= 0; i-- {
+		f, err := m.layers[i].Reader.lookup(op, name)
+		if err == nil {
+			return m.layers[i], f, nil
+		}
+	}
+
+	return nil, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
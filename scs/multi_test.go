@@ -0,0 +1,91 @@
+package scs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func writeTestTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+			t.Fatalf("creating directory: %s", err)
+		}
+
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatalf("writing file: %s", err)
+		}
+	}
+
+	return dir
+}
+
+func TestMultiReader(t *testing.T) {
+	base := writeTestTree(t, map[string]string{
+		"def/city.sii":    "base city",
+		"def/company.sii": "base company",
+	})
+	mod := writeTestTree(t, map[string]string{
+		"def/city.sii":     "mod city",
+		"def/mod/cars.sii": "mod cars",
+	})
+
+	m := NewMultiReader()
+	if err := m.AddDir(mod, PriorityMod); err != nil {
+		t.Fatalf("adding mod: %s", err)
+	}
+	if err := m.AddDir(base, PriorityBase); err != nil {
+		t.Fatalf("adding base: %s", err)
+	}
+
+	for name, expect := range map[string]string{
+		"def/city.sii":     "mod city",
+		"def/company.sii":  "base company",
+		"def/mod/cars.sii": "mod cars",
+	} {
+		data, err := m.ReadFile(name)
+		if err != nil {
+			t.Fatalf("reading %s: %s", name, err)
+		}
+
+		if string(data) != expect {
+			t.Errorf("unexpected content for %s: %q", name, data)
+		}
+	}
+
+	if l, _, err := m.Provider("def/company.sii"); err != nil || l.Name != base || l.Reader.Format() != "directory" {
+		t.Errorf("unexpected provider for base file: %v (%v)", l, err)
+	}
+
+	entries, err := m.ReadDir("def")
+	if err != nil || len(entries) != 3 { //nolint:mnd // city, company, mod
+		t.Errorf("unexpected merged directory listing: %v (%v)", entries, err)
+	}
+
+	var names []string
+	for _, f := range m.Files() {
+		if !f.IsDirectory {
+			names = append(names, f.Name)
+		}
+	}
+
+	if len(names) != 3 { //nolint:mnd
+		t.Errorf("unexpected effective files: %q", names)
+	}
+
+	if err = fstest.TestFS(m, "def/city.sii", "def/company.sii", "def/mod/cars.sii"); err != nil {
+		t.Errorf("filesystem test failed: %s", err)
+	}
+}
+
+func TestGameArchivePriority(t *testing.T) {
+	if !(GameArchivePriority("/game/base.scs") < GameArchivePriority("def.scs") &&
+		GameArchivePriority("def.scs") < GameArchivePriority("dlc_east.scs")) {
+		t.Error("unexpected archive order")
+	}
+}
@@ -0,0 +1,178 @@
+package scs
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// UnknownDir is the directory NameUnknown places files into whose
+// names could not be recovered
+const UnknownDir = "_unknown"
+
+var (
+	// referencePattern matches paths of game files referenced within
+	// definitions, materials, texture objects, models, ...
+	referencePattern = regexp.MustCompile(`(?i)[a-z0-9_./\-]*[a-z0-9_\-]\.(?:bank\.guids|bank|dds|font|guids|jpg|lua|mat|pma|pmc|pmd|pmg|png|ppd|sii|soundref|sui|tobj)\b`)
+
+	// relatedExtensions lists files usually existing next to a file
+	// with the given extension
+	relatedExtensions = map[string][]string{
+		".bank": {".bank.guids"},
+		".dds":  {".tobj", ".mat"},
+		".mat":  {".tobj", ".dds"},
+		".pmd":  {".pmg", ".pmc", ".pma", ".ppd"},
+		".pmg":  {".pmd", ".pmc", ".pma", ".ppd"},
+		".tobj": {".dds", ".mat"},
+	}
+)
+
+type recovery struct {
+	r       *Reader
+	unnamed map[uint64]*File
+	named   int
+}
+
+// ReferencedPaths extracts the paths of files referenced within the
+// content of the file with the given name (which might be empty if
+// unknown). Relative references are resolved against the directory of
+// the file and paths of related files (like the .dds for a .tobj) are
+// added as they are commonly present too. The result is meant to be
+// passed to RecoverNames and might contain paths of files not existing.
+func ReferencedPaths(from string, data []byte) []string {
+	var (
+		out  []string
+		seen = make(map[string]bool)
+		add  = func(name string) {
+			name = strings.Trim(path.Clean("/"+name), "/")
+			if name != "" && !seen[name] {
+				seen[name] = true
+				out = append(out, name)
+			}
+		}
+	)
+
+	for _, match := range referencePattern.FindAll(data, -1) {
+		ref := strings.ToLower(string(match))
+
+		candidates := []string{ref}
+		if !strings.HasPrefix(ref, "/") && from != "" {
+			candidates = append(candidates, path.Join(path.Dir(from), ref))
+		}
+
+		for _, c := range candidates {
+			add(c)
+
+			ext := path.Ext(c)
+			for _, related := range relatedExtensions[ext] {
+				add(strings.TrimSuffix(c, ext) + related)
+			}
+		}
+	}
+
+	return out
+}
+
+// NameUnknown assigns names to all files whose names could not be
+// recovered. They are placed inside the UnknownDir and named by their
+// hash with an extension guessed from their content.
+func (r *Reader) NameUnknown() {
+	for _, f := range r.Unnamed() {
+		if f.IsDirectory {
+			// Without a name the listing is of no use
+			continue
+		}
+
+		// Files which cannot be read are still named without extension
+		t, _ := f.Sniff() //nolint:errcheck
+		f.Name = fmt.Sprintf("%s/%016x%s", UnknownDir, f.Hash, t.Extension())
+
+		parent := r.virtualDir(UnknownDir)
+		parent.children = append(parent.children, path.Base(f.Name))
+		r.byName[f.Name] = f
+	}
+}
+
+// RecoverNames hashes the given candidate paths (i.e. from a list of
+// known paths or from ReferencedPaths) and names the entries matching
+// them. Listings of recovered directories are used to name their
+// contents. The number of newly named entries is returned.
+func (r *Reader) RecoverNames(candidates []string) int {
+	rec := &recovery{r: r, unnamed: make(map[uint64]*File)}
+	for _, f := range r.Unnamed() {
+		rec.unnamed[f.Hash] = f
+	}
+
+	for _, c := range candidates {
+		rec.tryName(strings.Trim(path.Clean("/"+c), "/"))
+	}
+
+	return rec.named
+}
+
+// Unnamed returns all entries of the archive whose names are unknown
+func (r *Reader) Unnamed() (files []*File) {
+	for _, f := range r.Files {
+		if f.Name == "" && f != r.root {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// attach registers the named file and links it into its parent
+// directory which is recovered or created if required
+func (rec *recovery) attach(f *File) {
+	rec.r.byName[f.Name] = f
+
+	dir := path.Dir(f.Name)
+	if dir == "." {
+		dir = ""
+	}
+
+	parent, ok := rec.r.byName[dir]
+	if !ok {
+		if rec.tryName(dir) {
+			parent = rec.r.byName[dir]
+		} else {
+			parent = rec.r.virtualDir(dir)
+		}
+	}
+
+	if base := path.Base(f.Name); !slices.Contains(parent.children, base) {
+		parent.children = append(parent.children, base)
+	}
+}
+
+// tryName names the unnamed entry matching the given name if there is
+// one and reports whether an entry was found
+func (rec *recovery) tryName(name string) bool {
+	hash := rec.r.hashPath(name)
+
+	f, ok := rec.unnamed[hash]
+	if !ok || name == "" {
+		return false
+	}
+
+	delete(rec.unnamed, hash)
+	f.Name = name
+	rec.named++
+	rec.attach(f)
+
+	if f.IsDirectory {
+		entries, err := rec.r.readDirListing(f)
+		if err != nil {
+			// The listing is broken, its entries might still be
+			// recovered by other candidates
+			return true
+		}
+
+		for _, e := range entries {
+			rec.tryName(path.Join(name, e.Name))
+		}
+	}
+
+	return true
+}
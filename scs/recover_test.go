@@ -0,0 +1,106 @@
+package scs
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReferencedPaths(t *testing.T) {
+	got := ReferencedPaths("def/vehicle/truck.sii", []byte(`SiiNunit {
+accessory_data : .truck {
+	model: "/vehicle/truck/model.pmd"
+	icon: "icon.mat"
+	@include "parts.sui"
+}
+}`))
+
+	for _, expect := range []string{
+		"vehicle/truck/model.pmd",
+		"vehicle/truck/model.pmg",
+		"def/vehicle/icon.mat",
+		"def/vehicle/icon.tobj",
+		"icon.mat",
+		"def/vehicle/parts.sui",
+	} {
+		if !slices.Contains(got, expect) {
+			t.Errorf("expected %q in %v", expect, got)
+		}
+	}
+}
+
+func TestRecoverNames(t *testing.T) {
+	data := writeTestArchive(t, map[string]string{
+		"def/city.sii":         `SiiNunit { city : .berlin { icon: "/material/ui/icon.mat" } }`,
+		"def/company.sii":      "SiiNunit { }",
+		"material/ui/icon.mat": "material",
+		"manifest.sii":         "SiiNunit { }",
+	})
+
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("reading archive: %s", err)
+	}
+
+	// Simulate an archive with stripped root listing
+	stripped := &Reader{archiveReader: r.archiveReader, version: r.version}
+	for _, f := range r.Files {
+		if f == r.root {
+			continue
+		}
+		f.Name, f.children = "", nil
+		stripped.Files = append(stripped.Files, f)
+	}
+
+	if err = stripped.populateFileNames(); err != nil {
+		t.Fatalf("populating names without root: %s", err)
+	}
+
+	if len(stripped.Warnings) != 1 || !errors.Is(stripped.Warnings[0], ErrNoRootListing) {
+		t.Errorf("expected missing root listing warning, got %v", stripped.Warnings)
+	}
+
+	// Naming the city recovers the def directory and its listing
+	if n := stripped.RecoverNames([]string{"def/city.sii", "does/not/exist.sii"}); n != 3 { //nolint:mnd
+		t.Errorf("expected 3 recovered entries, got %d", n)
+	}
+
+	city, err := stripped.ReadFile("def/city.sii")
+	if err != nil {
+		t.Fatalf("reading recovered file: %s", err)
+	}
+
+	if n := stripped.RecoverNames(ReferencedPaths("def/city.sii", city)); n != 3 { //nolint:mnd
+		t.Errorf("expected 3 recovered entries from references, got %d", n)
+	}
+
+	stripped.NameUnknown()
+
+	unknown := UnknownDir + "/" + "b97fff7ce7377c95.sii"
+	if _, err = stripped.Stat(unknown); err != nil {
+		t.Errorf("expected unknown manifest at %s: %s", unknown, err)
+	}
+
+	if err = fstest.TestFS(stripped, "def/city.sii", "def/company.sii", "material/ui/icon.mat", unknown); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReadArchiveWithoutRootListing(t *testing.T) {
+	r, err := NewReader(bytes.NewReader(writeV1Archive(t, []v1TestFile{
+		{name: "def/a.sii", content: "SiiNunit { }"},
+	})))
+	if err != nil {
+		t.Fatalf("reading archive: %s", err)
+	}
+
+	if len(r.Warnings) != 1 || !errors.Is(r.Warnings[0], ErrNoRootListing) {
+		t.Errorf("expected missing root listing warning, got %v", r.Warnings)
+	}
+
+	if unnamed := r.Unnamed(); len(unnamed) != 1 || unnamed[0].Size != 12 { //nolint:mnd
+		t.Errorf("expected the file to be unnamed, got %v", unnamed)
+	}
+}
@@ -11,16 +11,18 @@ import (
 	"io"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/Luzifer/scs-extract/b0rkhash"
 )
 
-const (
-	flagIsDirectory  = 0x10
-	supportedVersion = 0x2
-	zipHeaderSize    = 0x2
-)
+const zipHeaderSize = 0x2
+
+// ErrNoRootListing is added to the Warnings of archives without root
+// directory listing (i.e. protected mods): their entries have no names
+// until named by RecoverNames or NameUnknown
+var ErrNoRootListing = errors.New("no root directory listing found")
 
 type (
 	// File represents a file inside the SCS# archive
@@ -33,8 +35,18 @@ type (
 		IsDirectory    bool
 		Size           uint32
 
+		// Mips and Sampler describe image entries of HashFS v2
+		// archives and are nil for all other files
+		Mips    *MipLayout
+		Sampler *Sampler
+
 		archiveReader io.ReaderAt
+		children      []string
+		image         *imageMeta
+		isRoot        bool
+		metaType      catalogMetaEntryType
 		offset        uint64
+		open          func() (io.ReadCloser, error)
 	}
 
 	// Reader contains a parser for the archive and after creation will
@@ -42,13 +54,39 @@ type (
 	Reader struct {
 		Files []*File
 
-		header        fileHeader
-		entryTable    []catalogEntry
-		metadataTable map[uint32]catalogMetaEntry
+		// Warnings contains the problems skipped when reading the
+		// archive in Lenient mode and ErrNoRootListing for archives
+		// without root directory listing
+		Warnings []error
+
+		byName     map[string]*File
+		root       *File
+		salt       uint16
+		version    uint16
+		isDir      bool
+		lenient    bool
+		header     fileHeader
+		entryTable []catalogEntry
+		metaTable  *metaTable
 
 		archiveReader io.ReaderAt
 	}
 
+	// ReaderOption configures optional behavior of the Reader
+	ReaderOption func(*Reader)
+
+	archiveHeader struct {
+		Magic      [4]byte
+		Version    uint16
+		Salt       uint16
+		HashMethod [4]byte
+	}
+
+	dirListingEntry struct {
+		Name        string
+		IsDirectory bool
+	}
+
 	fileHeader struct {
 		Magic                    [4]byte
 		Version                  uint16
@@ -71,17 +109,6 @@ type (
 		Flags         uint16
 	}
 
-	catalogMetaEntry struct {
-		Index          uint32
-		Offset         uint64
-		CompressedSize uint32
-		Size           uint32
-		Flags          byte
-
-		IsDirectory  bool
-		IsCompressed bool
-	}
-
 	catalogMetaEntryType byte
 )
 
@@ -97,27 +124,59 @@ const (
 	metaEntryTypeMipTail         catalogMetaEntryType = 132
 )
 
+var metaEntryTypeNames = map[catalogMetaEntryType]string{
+	metaEntryTypeImage:           "image",
+	metaEntryTypeSample:          "sample",
+	metaEntryTypeMipProxy:        "mip_proxy",
+	metaEntryTypeInlineDirectory: "inline_directory",
+	metaEntryTypePlain:           "plain",
+	metaEntryTypeDirectory:       "directory",
+	metaEntryTypeMip0:            "mip0",
+	metaEntryTypeMip1:            "mip1",
+	metaEntryTypeMipTail:         "mip_tail",
+}
+
+const (
+	archiveVersion1 uint16 = 1
+	archiveVersion2 uint16 = 2
+)
+
 var (
+	ddsMagic      = []byte("DDS ")
 	scsMagic      = []byte("SCS#")
 	scsHashMethod = []byte("CITY")
-
-	localeRootPathHash = b0rkhash.CityHash64([]byte("locale"))
-	rootPathHash       = b0rkhash.CityHash64([]byte(""))
 )
 
+func (t catalogMetaEntryType) String() string {
+	if name, ok := metaEntryTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown_%d", t)
+}
+
 // NewReader opens the archive from the given io.ReaderAt and parses
-// the header information
-func NewReader(r io.ReaderAt) (out *Reader, err error) {
+// the header information. The archive version (HashFS v1 or v2) is
+// detected from the header. ZIP archives (as used by many mods) are
+// detected and read through the same interface.
+func NewReader(r io.ReaderAt, opts ...ReaderOption) (out *Reader, err error) {
 	// Read the header
-	var header fileHeader
+	var header archiveHeader
 	if err = binary.Read(
-		io.NewSectionReader(r, 0, int64(binary.Size(fileHeader{}))),
+		io.NewSectionReader(r, 0, int64(binary.Size(archiveHeader{}))),
 		binary.LittleEndian,
 		&header,
 	); err != nil {
 		return nil, fmt.Errorf("reading header: %w", err)
 	}
 
+	if bytes.Equal(header.Magic[:], zipMagic) {
+		out = newReader(r, opts)
+		if err = out.parseZip(); err != nil {
+			return nil, fmt.Errorf("parsing zip archive: %w", err)
+		}
+		return out, nil
+	}
+
 	// Sanity checks
 	if !bytes.Equal(header.Magic[:], scsMagic) {
 		return nil, fmt.Errorf("unexpected magic header")
@@ -127,55 +186,401 @@ func NewReader(r io.ReaderAt) (out *Reader, err error) {
 		return nil, fmt.Errorf("unexpected hash method")
 	}
 
-	if header.Version != supportedVersion {
+	// Do the real parsing
+	out = newReader(r, opts)
+	out.salt = header.Salt
+	out.version = header.Version
+
+	switch header.Version {
+	case archiveVersion1:
+		err = out.parseV1()
+
+	case archiveVersion2:
+		err = out.parseV2()
+
+	default:
 		return nil, fmt.Errorf("unsupported archive version: %d", header.Version)
 	}
 
-	// Do the real parsing
-	out = &Reader{
-		archiveReader: r,
-		header:        header,
+	if err != nil {
+		return nil, err
 	}
 
-	if err = out.parseEntryTable(); err != nil {
-		return nil, fmt.Errorf("parsing entry table: %w", err)
+	return out, out.populateFileNames()
+}
+
+// MetadataType returns the type of the metadata entry describing the
+// file in HashFS v2 archives (i.e. "plain", "directory" or "image").
+// For other sources "plain" or "directory" is returned.
+func (f *File) MetadataType() string {
+	switch {
+	case f.metaType != 0:
+		return f.metaType.String()
+	case f.IsDirectory:
+		return metaEntryTypeDirectory.String()
+	default:
+		return metaEntryTypePlain.String()
 	}
+}
 
-	if err = out.parseMetadataTable(); err != nil {
-		return nil, fmt.Errorf("parsing metadata table: %w", err)
+// IsRoot reports whether the file is the root directory of the archive
+func (f *File) IsRoot() bool { return f.isRoot }
+
+// Offset returns the position of the file data within the archive.
+// Files not stored in a HashFS archive report an offset of zero.
+func (f *File) Offset() uint64 { return f.offset }
+
+// Version returns the HashFS version of the archive or zero for ZIP
+// archives and directories
+func (r *Reader) Version() uint16 { return r.version }
+
+// Format describes the source of the files: "HashFS v1", "HashFS v2",
+// "ZIP" or "directory"
+func (r *Reader) Format() string {
+	switch {
+	case r.version > 0:
+		return fmt.Sprintf("HashFS v%d", r.version)
+	case r.isDir:
+		return "directory"
+	default:
+		return "ZIP"
 	}
+}
 
-	for _, e := range out.entryTable {
-		meta := out.metadataTable[e.MetadataIndex+uint32(e.MetadataCount)]
-		f := File{
-			CompressedSize: meta.CompressedSize,
-			Hash:           e.Hash,
-			IsCompressed:   meta.IsCompressed || (meta.Flags&flagIsDirectory) != 0,
-			IsDirectory:    meta.IsDirectory,
-			Size:           meta.Size,
-			archiveReader:  r,
-			offset:         meta.Offset,
-		}
+// Lenient makes the Reader tolerate metadata it cannot decode (i.e.
+// types introduced by newer game versions) and broken or cyclic
+// directory listings: instead of failing, the affected entries are
+// skipped and the problems are collected in Reader.Warnings.
+func Lenient() ReaderOption {
+	return func(r *Reader) { r.lenient = true }
+}
+
+func newReader(r io.ReaderAt, opts []ReaderOption) *Reader {
+	out := &Reader{archiveReader: r}
+	for _, opt := range opts {
+		opt(out)
+	}
+	return out
+}
 
-		out.Files = append(out.Files, &f)
+// warn records the problem as warning in lenient mode and returns it
+// otherwise
+func (r *Reader) warn(err error) error {
+	if !r.lenient {
+		return err
 	}
 
-	return out, out.populateFileNames()
+	r.Warnings = append(r.Warnings, err)
+	return nil
 }
 
 // Open opens the file for reading
 func (f *File) Open() (io.ReadCloser, error) {
-	var rc io.ReadCloser
+	switch {
+	case f.open != nil:
+		return f.open()
+
+	case f.archiveReader == nil:
+		// Virtual entry (i.e. directory of a ZIP archive) without data
+		return io.NopCloser(bytes.NewReader(nil)), nil
+
+	case f.image != nil:
+		return f.openImage()
+	}
+
+	return f.openRaw(f.Size), nil
+}
 
+func (f *File) openImage() (io.ReadCloser, error) {
+	dds := f.image.canBuildDDS()
+
+	parts := make([]mipData, 0, len(f.Mips.Chunks))
+	for _, c := range f.Mips.Chunks {
+		// For images the size within the archive is not the size we
+		// present to the outside as we're adding the DDS header
+		size := c.chunk.Size
+		if dds {
+			size = c.CompressedSize
+		}
+
+		raw := f.openChunk(c.chunk, size)
+		data, err := io.ReadAll(raw)
+		raw.Close() //nolint:errcheck,gosec // Data is already read
+		if err != nil {
+			return nil, fmt.Errorf("reading image data of mip %d: %w", c.FirstMip, err)
+		}
+
+		parts = append(parts, mipData{first: c.FirstMip, count: c.MipCount, data: data})
+	}
+
+	if !dds {
+		// Unknown pixel formats are passed through as stored
+		var buf bytes.Buffer
+		for _, p := range parts {
+			buf.Write(p.data)
+		}
+		return io.NopCloser(&buf), nil
+	}
+
+	data, err := f.image.buildSplitDDS(parts)
+	if err != nil {
+		return nil, fmt.Errorf("building DDS: %w", err)
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// openChunk opens the data chunk within the archive, size is the
+// number of bytes to read from uncompressed chunks
+func (f *File) openChunk(c metaChunk, size uint32) io.ReadCloser {
+	if c.Flags&metaFlagCompressed != 0 {
+		r := io.NewSectionReader(f.archiveReader, int64(c.Offset+zipHeaderSize), int64(c.CompressedSize)) //#nosec:G115 // int64 wraps at 9EB - We don't have to care for a LONG time
+		return flate.NewReader(r)
+	}
+
+	r := io.NewSectionReader(f.archiveReader, int64(c.Offset), int64(size)) //#nosec:G115 // int64 wraps at 9EB - We don't have to care for a LONG time
+	return io.NopCloser(r)
+}
+
+// chunk returns the location of the file data within the archive
+func (f *File) chunk() metaChunk {
+	c := metaChunk{CompressedSize: f.CompressedSize, Size: f.Size, Offset: f.offset}
 	if f.IsCompressed {
-		r := io.NewSectionReader(f.archiveReader, int64(f.offset+zipHeaderSize), int64(f.CompressedSize)) //#nosec:G115 // int64 wraps at 9EB - We don't have to care for a LONG time
-		rc = flate.NewReader(r)
-	} else {
-		r := io.NewSectionReader(f.archiveReader, int64(f.offset), int64(f.Size)) //#nosec:G115 // int64 wraps at 9EB - We don't have to care for a LONG time
-		rc = io.NopCloser(r)
+		c.Flags = metaFlagCompressed
+	}
+	return c
+}
+
+func (f *File) openRaw(size uint32) io.ReadCloser {
+	return f.openChunk(f.chunk(), size)
+}
+
+func (r *Reader) parseV2() (err error) {
+	if err = binary.Read(
+		io.NewSectionReader(r.archiveReader, 0, int64(binary.Size(fileHeader{}))),
+		binary.LittleEndian,
+		&r.header,
+	); err != nil {
+		return fmt.Errorf("reading header: %w", err)
+	}
+
+	if err = r.parseEntryTable(); err != nil {
+		return fmt.Errorf("parsing entry table: %w", err)
+	}
+
+	if err = r.parseMetadataTable(); err != nil {
+		return fmt.Errorf("parsing metadata table: %w", err)
+	}
+
+	for _, e := range r.entryTable {
+		f, err := r.fileFromMetadata(e)
+		if err != nil {
+			if err = r.warn(err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		r.Files = append(r.Files, f)
 	}
 
-	return rc, nil
+	return r.resolveMipProxies()
+}
+
+// fileFromMetadata creates the File for the catalog entry from its
+// metadata entries
+//
+//nolint:gocyclo // Mostly a switch over the metadata types
+func (r *Reader) fileFromMetadata(e catalogEntry) (*File, error) {
+	var (
+		chunk          *metaChunk
+		mips           = make(map[catalogMetaEntryType]*metaChunk)
+		metaType       catalogMetaEntryType
+		image          *imageMeta
+		proxy          uint64
+		raw            []RawMetadata
+		sampler        *Sampler
+		metadataErrorf = func(format string, a ...any) error {
+			return &MetadataError{Hash: e.Hash, Err: fmt.Errorf(format, a...)}
+		}
+	)
+
+	for _, h := range r.metaTable.headers(e) {
+		switch h.Type {
+		case metaEntryTypeImage:
+			w, err := r.metaTable.payload(h.Index, metaImageWords)
+			if err != nil {
+				return nil, &MetadataError{Hash: e.Hash, Err: err}
+			}
+			image = newImageMeta(uint16(w[0]), uint16(w[0]>>16), w[1]) //nolint:mnd
+			metaType = h.Type
+
+		case metaEntryTypeSample:
+			w, err := r.metaTable.payload(h.Index, metaSampleWords)
+			if err != nil {
+				return nil, &MetadataError{Hash: e.Hash, Err: err}
+			}
+			sampler = newSampler(w[0])
+
+		case metaEntryTypeMipProxy:
+			w, err := r.metaTable.payload(h.Index, metaMipProxyWords)
+			if err != nil {
+				return nil, &MetadataError{Hash: e.Hash, Err: err}
+			}
+			proxy = uint64(w[0]) | uint64(w[1])<<32 //nolint:mnd
+
+		case metaEntryTypeMip0, metaEntryTypeMip1, metaEntryTypeMipTail:
+			if mips[h.Type] != nil {
+				return nil, metadataErrorf("duplicate %s metadata", h.Type)
+			}
+
+			c, err := r.metaTable.chunk(h.Index)
+			if err != nil {
+				return nil, &MetadataError{Hash: e.Hash, Err: err}
+			}
+			mips[h.Type] = &c
+
+		case metaEntryTypePlain, metaEntryTypeDirectory:
+			if chunk != nil {
+				raw = append(raw, r.metaTable.raw(h))
+				continue
+			}
+
+			c, err := r.metaTable.chunk(h.Index)
+			if err != nil {
+				return nil, &MetadataError{Hash: e.Hash, Err: err}
+			}
+			chunk = &c
+
+			if metaType == 0 || h.Type == metaEntryTypeDirectory {
+				metaType = h.Type
+			}
+
+		default:
+			raw = append(raw, r.metaTable.raw(h))
+		}
+	}
+
+	if len(raw) > 0 {
+		// We must not present partially decoded files as their content
+		// might be incomplete or wrong
+		types := make([]string, len(raw))
+		for i := range raw {
+			types[i] = catalogMetaEntryType(raw[i].Type).String()
+		}
+		return nil, &MetadataError{Hash: e.Hash, Raw: raw, Err: fmt.Errorf("unhandled metadata types: %s", strings.Join(types, ", "))}
+	}
+
+	if image == nil {
+		return r.plainFileFromMetadata(e, metaType, chunk, mips, proxy)
+	}
+
+	if chunk != nil {
+		// Images stored in a single chunk contain all mip levels
+		if mips[metaEntryTypeMipTail] != nil {
+			return nil, metadataErrorf("image stored in plain and mip tail chunk")
+		}
+		mips[metaEntryTypeMipTail] = chunk
+	}
+
+	f := &File{
+		Hash:          e.Hash,
+		Mips:          newMipLayout(image, mips[metaEntryTypeMip0], mips[metaEntryTypeMip1], mips[metaEntryTypeMipTail]),
+		Sampler:       sampler,
+		archiveReader: r.archiveReader,
+		metaType:      metaType,
+	}
+	f.Mips.Proxy = proxy
+
+	if len(f.Mips.Chunks) == 0 && proxy == 0 {
+		return nil, metadataErrorf("no data location in metadata")
+	}
+
+	f.setImageChunks(image)
+	return f, nil
+}
+
+// plainFileFromMetadata creates the File for entries which are not
+// images and therefore must not contain image related metadata
+func (r *Reader) plainFileFromMetadata(
+	e catalogEntry,
+	metaType catalogMetaEntryType,
+	chunk *metaChunk,
+	mips map[catalogMetaEntryType]*metaChunk,
+	proxy uint64,
+) (*File, error) {
+	if len(mips) > 0 || proxy != 0 {
+		return nil, &MetadataError{Hash: e.Hash, Err: fmt.Errorf("mip metadata without image")}
+	}
+
+	if chunk == nil {
+		return nil, &MetadataError{Hash: e.Hash, Err: fmt.Errorf("no data location in metadata")}
+	}
+
+	return &File{
+		CompressedSize: chunk.CompressedSize,
+		Hash:           e.Hash,
+		IsCompressed:   chunk.Flags&metaFlagCompressed != 0,
+		IsDirectory:    metaType == metaEntryTypeDirectory,
+		Size:           chunk.Size,
+		archiveReader:  r.archiveReader,
+		metaType:       metaType,
+		offset:         chunk.Offset,
+	}, nil
+}
+
+// resolveMipProxies attaches the data chunks of the entries referenced
+// by mip proxies to the images using them
+func (r *Reader) resolveMipProxies() error {
+	byHash := make(map[uint64]*File, len(r.Files))
+	for _, f := range r.Files {
+		byHash[f.Hash] = f
+	}
+
+	files := r.Files[:0]
+	for _, f := range r.Files {
+		if f.Mips == nil || f.Mips.Proxy == 0 || len(f.Mips.Chunks) > 0 {
+			files = append(files, f)
+			continue
+		}
+
+		target := byHash[f.Mips.Proxy]
+		if target == nil || target.Mips == nil || len(target.Mips.Chunks) == 0 || target.Mips.Proxy != 0 {
+			if err := r.warn(&MetadataError{Hash: f.Hash, Err: fmt.Errorf("mip proxy references missing image %016x", f.Mips.Proxy)}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		f.Mips.Chunks = target.Mips.Chunks
+		f.setImageChunks(f.image)
+		files = append(files, f)
+	}
+
+	r.Files = files
+	return nil
+}
+
+// setImageChunks sets the location and size of the image data from
+// its mip chunks
+func (f *File) setImageChunks(image *imageMeta) {
+	f.image = image
+	f.CompressedSize, f.Size = 0, 0
+
+	for i, c := range f.Mips.Chunks {
+		if i == 0 {
+			f.offset = c.chunk.Offset
+			f.IsCompressed = c.IsCompressed
+		}
+		f.CompressedSize += c.CompressedSize
+		f.Size += c.chunk.Size
+	}
+
+	if image.canBuildDDS() {
+		// Image entries only contain the pixel data, we will
+		// present them as DDS files with rebuilt headers
+		f.Size = image.ddsSize()
+	}
 }
 
 func (r *Reader) parseEntryTable() error {
@@ -205,8 +610,6 @@ func (r *Reader) parseEntryTable() error {
 }
 
 func (r *Reader) parseMetadataTable() error {
-	r.metadataTable = make(map[uint32]catalogMetaEntry)
-
 	mtReader, err := zlib.NewReader(io.NewSectionReader(
 		r.archiveReader,
 		int64(r.header.MetadataTableStart), //#nosec:G115 // int64 wraps at 9EB - We don't have to care for a LONG time
@@ -217,58 +620,29 @@ func (r *Reader) parseMetadataTable() error {
 	}
 	defer mtReader.Close() //nolint:errcheck
 
-	for {
-		var metaType metaEntryType
-		if err = binary.Read(mtReader, binary.LittleEndian, &metaType); err != nil {
-			if errors.Is(err, io.EOF) {
-				return nil
-			}
-			return fmt.Errorf("reading meta-type-header: %w", err)
-		}
-
-		var payload iMetaEntry
-		switch metaType.Type {
-		case metaEntryTypeDirectory:
-			var p metaEntryDir
-			if err = binary.Read(mtReader, binary.LittleEndian, &p); err != nil {
-				return fmt.Errorf("reading dir definition: %w", err)
-			}
-			payload = metaEntry{t: metaType, p: p}
-
-		case metaEntryTypePlain:
-			var p metaEntryFile
-			if err = binary.Read(mtReader, binary.LittleEndian, &p); err != nil {
-				return fmt.Errorf("reading file definition: %w", err)
-			}
-			payload = metaEntry{t: metaType, p: p}
-
-		case metaEntryTypeImage:
-			var p metaEntryImage
-			if err = binary.Read(mtReader, binary.LittleEndian, &p); err != nil {
-				return fmt.Errorf("reading image definition: %w", err)
-			}
-			payload = metaEntry{t: metaType, p: p}
-
-		default:
-			return fmt.Errorf("unhandled file type: %v", metaType.Type)
-		}
-
-		var e catalogMetaEntry
-		payload.Fill(&e)
+	data, err := io.ReadAll(mtReader)
+	if err != nil {
+		return fmt.Errorf("reading metadata table: %w", err)
+	}
 
-		r.metadataTable[e.Index] = e
+	words := make([]uint32, len(data)/4) //nolint:mnd
+	if err = binary.Read(bytes.NewReader(data), binary.LittleEndian, words); err != nil {
+		return fmt.Errorf("decoding metadata table: %w", err)
 	}
+
+	r.metaTable = newMetaTable(words, r.entryTable)
+	return nil
 }
 
 func (r *Reader) populateFileNames() (err error) {
-	// first seek root entry, without the archive is not usable for us
+	// first seek root entry to walk the directory listings from
 	var entry *File
 	for _, f := range r.Files {
-		if f.Hash == rootPathHash {
+		if f.Hash == r.hashPath("") {
 			entry = f
 			entry.Name = ""
 			break
-		} else if f.Hash == localeRootPathHash {
+		} else if f.Hash == r.hashPath("locale") {
 			entry = f
 			entry.Name = "locale"
 			break
@@ -276,57 +650,99 @@ func (r *Reader) populateFileNames() (err error) {
 	}
 
 	if entry == nil {
-		// We found no suitable entrypoint
-		return fmt.Errorf("no root entry found")
+		// Listings were stripped (as done by some protected mods) so
+		// we can only serve the files named by RecoverNames
+		r.Warnings = append(r.Warnings, ErrNoRootListing)
+		r.root = &File{IsDirectory: true, isRoot: true}
+		r.byName = map[string]*File{"": r.root}
+		return nil
 	}
 
-	if err = r.setFilenamesFromDir(entry); err != nil {
+	r.byName = map[string]*File{entry.Name: entry}
+	r.root = entry
+	if entry.Name != "" {
+		// The archive has no real root (i.e. locale.scs) so we need
+		// a virtual one to be able to walk the archive as a filesystem
+		r.root = &File{IsDirectory: true, children: []string{entry.Name}}
+		r.byName[""] = r.root
+	}
+	r.root.isRoot = true
+
+	if err = r.setFilenamesFromDir(entry, map[*File]bool{}); err != nil {
 		return fmt.Errorf("setting filenames: %w", err)
 	}
 
 	return nil
 }
 
-func (r *Reader) setFilenamesFromDir(node *File) error {
+func (r *Reader) hashPath(name string) uint64 {
+	if r.salt != 0 {
+		name = strconv.Itoa(int(r.salt)) + name
+	}
+
+	return b0rkhash.CityHash64([]byte(name))
+}
+
+func (r *Reader) readDirListing(node *File) ([]dirListingEntry, error) {
+	if r.version == archiveVersion1 {
+		return r.readDirListingV1(node)
+	}
+
 	f, err := node.Open()
 	if err != nil {
-		return fmt.Errorf("opening file: %w", err)
+		return nil, fmt.Errorf("opening file: %w", err)
 	}
 	defer f.Close() //nolint:errcheck
 
 	var entryCount uint32
 	if err = binary.Read(f, binary.LittleEndian, &entryCount); err != nil {
-		return fmt.Errorf("reading entry count: %w", err)
+		return nil, fmt.Errorf("reading entry count: %w", err)
 	}
 
 	if entryCount == 0 {
 		// Listing without any files
-		return fmt.Errorf("no entries in directory listing")
+		return nil, fmt.Errorf("no entries in directory listing")
 	}
 
 	stringLengths := make([]byte, entryCount)
 	if err = binary.Read(f, binary.LittleEndian, &stringLengths); err != nil {
-		return fmt.Errorf("reading string lengths: %w", err)
+		return nil, fmt.Errorf("reading string lengths: %w", err)
 	}
 
+	entries := make([]dirListingEntry, 0, entryCount)
 	for i := uint32(0); i < entryCount; i++ {
-		var (
-			hash    uint64
-			name    = make([]byte, stringLengths[i])
-			recurse bool
-		)
-
+		name := make([]byte, stringLengths[i])
 		if err = binary.Read(f, binary.LittleEndian, &name); err != nil {
-			return fmt.Errorf("reading name: %w", err)
+			return nil, fmt.Errorf("reading name: %w", err)
 		}
 
-		if name[0] == '/' {
+		if len(name) > 0 && name[0] == '/' {
 			// Directory entry
-			recurse = true
-			name = name[1:]
+			entries = append(entries, dirListingEntry{Name: string(name[1:]), IsDirectory: true})
+			continue
 		}
 
-		hash = b0rkhash.CityHash64([]byte(strings.TrimPrefix(path.Join(node.Name, string(name)), "/")))
+		entries = append(entries, dirListingEntry{Name: string(name)})
+	}
+
+	return entries, nil
+}
+
+// setFilenamesFromDir names the entries of the listing of node and
+// descends into the listed directories. Entries resolving to an entry
+// already visited (i.e. "/" or "*" entries referencing the directory
+// itself in hostile archives) are rejected as they would never end.
+func (r *Reader) setFilenamesFromDir(node *File, visited map[*File]bool) error {
+	visited[node] = true
+
+	entries, err := r.readDirListing(node)
+	if err != nil {
+		return r.warn(fmt.Errorf("reading listing of %q: %w", node.Name, err))
+	}
+
+	for _, entry := range entries {
+		name := strings.TrimPrefix(path.Join(node.Name, entry.Name), "/")
+		hash := r.hashPath(name)
 
 		var next *File
 		for _, rf := range r.Files {
@@ -337,12 +753,25 @@ func (r *Reader) setFilenamesFromDir(node *File) error {
 		}
 
 		if next == nil {
-			return fmt.Errorf("reference to void: %s", path.Join(node.Name, string(name)))
+			if err = r.warn(fmt.Errorf("reference to void: %s", path.Join(node.Name, entry.Name))); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if visited[next] {
+			if err = r.warn(fmt.Errorf("cyclic directory listing: %s", path.Join(node.Name, entry.Name))); err != nil {
+				return err
+			}
+			continue
 		}
 
-		next.Name = strings.TrimPrefix(path.Join(node.Name, string(name)), "/")
-		if recurse {
-			if err = r.setFilenamesFromDir(next); err != nil {
+		next.Name = name
+		node.children = append(node.children, entry.Name)
+		r.byName[next.Name] = next
+
+		if entry.IsDirectory {
+			if err = r.setFilenamesFromDir(next, visited); err != nil {
 				return err
 			}
 		}
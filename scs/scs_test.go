@@ -0,0 +1,67 @@
+package scs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// cyclicArchives returns archives whose listings contain an entry
+// resolving to the listed directory itself
+func cyclicArchives(t *testing.T) map[string][]byte {
+	t.Helper()
+
+	v2 := writeTestArchive(t, map[string]string{"def/b": "content"})
+
+	// The listing of def is too short to be compressed: replace its only
+	// entry by "/" which resolves to def again
+	listing := []byte{1, 0, 0, 0, 1, 'b'}
+	if bytes.Count(v2, listing) != 1 {
+		t.Fatal("listing of def not found")
+	}
+	v2 = bytes.Replace(v2, listing, []byte{1, 0, 0, 0, 1, '/'}, 1)
+
+	return map[string][]byte{
+		"v1": writeV1Archive(t, []v1TestFile{
+			{name: "", content: "def\n*\n", flags: v1FlagIsDirectory},
+			{name: "def", content: "SiiNunit { }"},
+		}),
+		"v2": v2,
+	}
+}
+
+func TestCyclicDirectoryListing(t *testing.T) {
+	for version, data := range cyclicArchives(t) {
+		_, err := NewReader(bytes.NewReader(data))
+		if err == nil || !strings.Contains(err.Error(), "cyclic directory listing") {
+			t.Errorf("%s: expected cyclic listing error, got %v", version, err)
+		}
+	}
+}
+
+func TestMetaChunkFlags(t *testing.T) {
+	for _, c := range []metaChunk{
+		{CompressedSize: maxCompressedSize, Size: 1, Offset: 0x40},
+		{CompressedSize: maxCompressedSize, Flags: metaFlagCompressed, Size: 1, Offset: 0x40},
+		{CompressedSize: 0x10, Flags: metaFlagCompressed, Size: 0x20, Offset: 0x50},
+	} {
+		decoded, err := newMetaTable(c.words(), nil).chunk(0)
+		if err != nil {
+			t.Fatalf("decoding chunk: %s", err)
+		}
+
+		if decoded != c {
+			t.Errorf("chunk changed in round trip: %+v != %+v", decoded, c)
+		}
+	}
+
+	// Upper size bits must not be read as flags
+	c, err := newMetaTable([]uint32{0x0f000000, 0, 0, 0}, nil).chunk(0)
+	if err != nil {
+		t.Fatalf("decoding chunk: %s", err)
+	}
+
+	if c.Flags != 0 || c.CompressedSize != 0x0f000000 {
+		t.Errorf("size and flags overlap: %+v", c)
+	}
+}
@@ -0,0 +1,191 @@
+package scs
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const globRecursive = "**"
+
+type (
+	// Selector decides which files of an archive to process. A file is
+	// selected when it matches at least one include pattern (or no
+	// include patterns are given) and no exclude pattern.
+	//
+	// Patterns are interpreted as follows:
+	//
+	//   - "def/vehicle/" (trailing slash) selects the whole subtree
+	//   - "def/**/*.sii" is a shell-style glob where "**" matches any
+	//     number of directories and "*", "?" and "[...]" work as in
+	//     path.Match
+	//   - anything else has to match the file name exactly or, naming
+	//     a directory, selects its whole subtree like with trailing
+	//     slash
+	//
+	// Additionally regular expressions can be given which are matched
+	// against the full file name.
+	Selector struct {
+		include []matcher
+		exclude []matcher
+	}
+
+	matcher func(name string) bool
+)
+
+// NewSelector creates a Selector including the given patterns
+func NewSelector(patterns ...string) (*Selector, error) {
+	s := &Selector{}
+	for _, p := range patterns {
+		if err := s.Include(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Exclude adds a pattern for files to skip
+func (s *Selector) Exclude(pattern string) error {
+	m, err := newPatternMatcher(pattern)
+	if err != nil {
+		return err
+	}
+
+	s.exclude = append(s.exclude, m)
+	return nil
+}
+
+// ExcludeRegexp adds a regular expression for files to skip
+func (s *Selector) ExcludeRegexp(expr string) error {
+	m, err := newRegexpMatcher(expr)
+	if err != nil {
+		return err
+	}
+
+	s.exclude = append(s.exclude, m)
+	return nil
+}
+
+// Filter returns the files selected by the Selector. Directories are
+// matched like files.
+func (s *Selector) Filter(files []*File) []*File {
+	var out []*File
+	for _, f := range files {
+		if s.Match(f.Name) {
+			out = append(out, f)
+		}
+	}
+
+	return out
+}
+
+// Include adds a pattern for files to select
+func (s *Selector) Include(pattern string) error {
+	m, err := newPatternMatcher(pattern)
+	if err != nil {
+		return err
+	}
+
+	s.include = append(s.include, m)
+	return nil
+}
+
+// IncludeRegexp adds a regular expression for files to select
+func (s *Selector) IncludeRegexp(expr string) error {
+	m, err := newRegexpMatcher(expr)
+	if err != nil {
+		return err
+	}
+
+	s.include = append(s.include, m)
+	return nil
+}
+
+// Match reports whether the file with the given name is selected
+func (s *Selector) Match(name string) bool {
+	if s == nil {
+		return true
+	}
+
+	for _, m := range s.exclude {
+		if m(name) {
+			return false
+		}
+	}
+
+	if len(s.include) == 0 {
+		return true
+	}
+
+	for _, m := range s.include {
+		if m(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newPatternMatcher(pattern string) (matcher, error) {
+	switch {
+	case pattern == "":
+		return nil, fmt.Errorf("empty pattern")
+
+	case strings.HasSuffix(pattern, "/"):
+		prefix := strings.TrimPrefix(pattern, "/")
+		return func(name string) bool { return strings.HasPrefix(name, prefix) }, nil
+
+	case strings.ContainsAny(pattern, "*?["):
+		segments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+		for _, seg := range segments {
+			if _, err := path.Match(seg, ""); err != nil {
+				return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+			}
+		}
+		return func(name string) bool { return matchGlob(segments, strings.Split(name, "/")) }, nil
+
+	default:
+		exact := strings.TrimPrefix(pattern, "/")
+		return func(name string) bool {
+			return name == exact || strings.HasPrefix(name, exact+"/")
+		}, nil
+	}
+}
+
+func newRegexpMatcher(expr string) (matcher, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %w", expr, err)
+	}
+
+	return re.MatchString, nil
+}
+
+// matchGlob matches the path segments of a name against the segments
+// of a glob pattern, "**" segments match zero or more name segments
+func matchGlob(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == globRecursive {
+			for i := 0; i <= len(name); i++ {
+				if matchGlob(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
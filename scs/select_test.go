@@ -0,0 +1,69 @@
+package scs
+
+import "testing"
+
+func TestSelector(t *testing.T) {
+	s, err := NewSelector("def/**/*.sii", "material/ui/", "manifest.sii", "/model/truck")
+	if err != nil {
+		t.Fatalf("creating selector: %s", err)
+	}
+
+	if err = s.Exclude("def/world/**"); err != nil {
+		t.Fatalf("adding exclude: %s", err)
+	}
+
+	if err = s.IncludeRegexp(`^sound/.*\.bank$`); err != nil {
+		t.Fatalf("adding regexp: %s", err)
+	}
+
+	for name, expect := range map[string]bool{
+		"def/city.sii":               true,
+		"def/vehicle/truck/data.sii": true,
+		"def/vehicle/truck/data.sui": false,
+		"def/world/prefab.sii":       false,
+		"material/ui/icon.mat":       true,
+		"material/ui/sub/icon.tobj":  true,
+		"material/uix/icon.mat":      false,
+		"manifest.sii":               true,
+		"other/manifest.sii":         false,
+		"sound/truck/engine.bank":    true,
+		"sound/truck/engine.txt":     false,
+		"model/truck":                true,
+		"model/truck/cabin.pmd":      true,
+		"model/truck/cabin/a.pmg":    true,
+		"model/trucks/cabin.pmd":     false,
+	} {
+		if got := s.Match(name); got != expect {
+			t.Errorf("Match(%q) = %v, expected %v", name, got, expect)
+		}
+	}
+}
+
+func TestSelectorEmpty(t *testing.T) {
+	s, err := NewSelector()
+	if err != nil {
+		t.Fatalf("creating selector: %s", err)
+	}
+
+	if !s.Match("anything/at/all.txt") {
+		t.Error("empty selector should select everything")
+	}
+
+	if err = s.ExcludeRegexp(`\.txt$`); err != nil {
+		t.Fatalf("adding exclude: %s", err)
+	}
+
+	if s.Match("anything/at/all.txt") {
+		t.Error("excluded file should not be selected")
+	}
+}
+
+func TestSelectorInvalidPatterns(t *testing.T) {
+	if _, err := NewSelector("def/[.sii"); err == nil {
+		t.Error("expected error for invalid glob")
+	}
+
+	if err := (&Selector{}).IncludeRegexp("("); err == nil {
+		t.Error("expected error for invalid regexp")
+	}
+}
@@ -0,0 +1,208 @@
+package scs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// sniffSize is the number of bytes read from the start of a file to
+// detect its type
+const sniffSize = 512
+
+// File types detected by Sniff
+const (
+	TypeUnknown FileType = iota
+	TypeSII
+	TypeDDS
+	TypePMG
+	TypePMD
+	TypePMA
+	TypePMC
+	TypeOGG
+	TypeBank
+	TypeTOBJ
+	TypeMAT
+	TypeFont
+	TypeLua
+	TypeText
+)
+
+// Versions of the model formats which do not have a magic. These are
+// the versions used by the current game data.
+const (
+	pmdVersion = 4
+	pmaVersion = 3
+	pmcVersion = 6
+)
+
+type (
+	// FileType is the type of file detected from its content
+	FileType int
+
+	fileTypeInfo struct {
+		name      string
+		extension string
+	}
+)
+
+var (
+	fileTypes = map[FileType]fileTypeInfo{
+		TypeUnknown: {"unknown", ""},
+		TypeSII:     {"sii", ".sii"},
+		TypeDDS:     {"dds", ".dds"},
+		TypePMG:     {"pmg", ".pmg"},
+		TypePMD:     {"pmd", ".pmd"},
+		TypePMA:     {"pma", ".pma"},
+		TypePMC:     {"pmc", ".pmc"},
+		TypeOGG:     {"ogg", ".ogg"},
+		TypeBank:    {"bank", ".bank"},
+		TypeTOBJ:    {"tobj", ".tobj"},
+		TypeMAT:     {"mat", ".mat"},
+		TypeFont:    {"font", ".font"},
+		TypeLua:     {"lua", ".lua"},
+		TypeText:    {"text", ".txt"},
+	}
+
+	magicSII = [][]byte{[]byte("SiiNunit"), []byte("ScsC"), []byte("BSII")}
+
+	magicOGG  = []byte("OggS")
+	magicPMG  = []byte("Gmp")
+	magicRIFF = []byte("RIFF")
+	magicFEV  = []byte("FEV ")
+	magicTOBJ = []byte{0x01, 0x0a, 0xb1, 0x70}
+
+	utf8BOM = []byte{0xef, 0xbb, 0xbf}
+)
+
+// Extension returns the file extension (including the dot) commonly
+// used for the type or an empty string for unknown files
+func (t FileType) Extension() string { return fileTypes[t].extension }
+
+// String returns the name of the type
+func (t FileType) String() string {
+	if info, ok := fileTypes[t]; ok {
+		return info.name
+	}
+	return fmt.Sprintf("FileType(%d)", int(t))
+}
+
+// Sniff detects the type of a file from the start of its content. At
+// least the first 512 byte should be passed for reliable results on
+// text files. Binary model files without magic (PMD, PMA, PMC) are
+// detected by their version number and therefore less reliable.
+func Sniff(data []byte) FileType {
+	for _, magic := range magicSII {
+		if bytes.HasPrefix(data, magic) {
+			return TypeSII
+		}
+	}
+
+	switch {
+	case bytes.HasPrefix(data, ddsMagic):
+		return TypeDDS
+
+	case bytes.HasPrefix(data, magicOGG):
+		return TypeOGG
+
+	case bytes.HasPrefix(data, magicTOBJ):
+		return TypeTOBJ
+
+	case bytes.HasPrefix(data, magicRIFF) && len(data) >= 12 && bytes.Equal(data[8:12], magicFEV): //nolint:mnd
+		// FMOD sound banks are RIFF containers of type "FEV "
+		return TypeBank
+
+	case len(data) >= 4 && bytes.Equal(data[1:4], magicPMG): //nolint:mnd
+		// PMG starts with a version byte followed by "Gmp"
+		return TypePMG
+	}
+
+	if isText(data) {
+		return sniffText(data)
+	}
+
+	if len(data) >= 4 { //nolint:mnd
+		switch binary.LittleEndian.Uint32(data) {
+		case pmdVersion:
+			return TypePMD
+		case pmaVersion:
+			return TypePMA
+		case pmcVersion:
+			return TypePMC
+		}
+	}
+
+	return TypeUnknown
+}
+
+// Sniff detects the type of the file from its content. See Sniff for
+// details.
+func (f *File) Sniff() (FileType, error) {
+	if f.image != nil && f.image.canBuildDDS() {
+		// The DDS header is built from the metadata, no need to read
+		return TypeDDS, nil
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return TypeUnknown, fmt.Errorf("opening file: %w", err)
+	}
+	defer rc.Close() //nolint:errcheck
+
+	head, err := io.ReadAll(io.LimitReader(rc, sniffSize))
+	if err != nil {
+		return TypeUnknown, fmt.Errorf("reading file: %w", err)
+	}
+
+	return Sniff(head), nil
+}
+
+// isText reports whether the data looks like UTF-8 text without
+// control characters other than whitespace
+func isText(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size == 1 {
+			// The sniffed data might end within a multi byte sequence
+			return len(data) < utf8.UTFMax && !utf8.FullRune(data)
+		}
+
+		if r < ' ' && r != '\t' && r != '\n' && r != '\r' {
+			return false
+		}
+
+		data = data[size:]
+	}
+
+	return true
+}
+
+// sniffText distinguishes the text based formats by their typical
+// content
+func sniffText(data []byte) FileType {
+	text := bytes.TrimLeft(bytes.TrimPrefix(data, utf8BOM), " \t\r\n")
+
+	switch {
+	case bytes.HasPrefix(text, []byte("SiiNunit")):
+		return TypeSII
+
+	case bytes.HasPrefix(text, []byte("material")), bytes.HasPrefix(text, []byte("effect")):
+		return TypeMAT
+
+	case bytes.Contains(text, []byte("vert_span:")), bytes.Contains(text, []byte("line_spacing:")):
+		return TypeFont
+
+	case bytes.HasPrefix(text, []byte("--")),
+		bytes.Contains(text, []byte("function ")) && bytes.Contains(text, []byte("end")),
+		bytes.HasPrefix(text, []byte("local ")):
+		return TypeLua
+	}
+
+	return TypeText
+}
@@ -0,0 +1,37 @@
+package scs
+
+import "testing"
+
+func TestSniff(t *testing.T) {
+	for name, tc := range map[string]struct {
+		data   []byte
+		expect FileType
+	}{
+		"sii text":      {[]byte("SiiNunit\n{\n}\n"), TypeSII},
+		"sii bom":       {[]byte("\xef\xbb\xbf\n SiiNunit {"), TypeSII},
+		"sii encrypted": {[]byte("ScsC\x00\x01\x02"), TypeSII},
+		"sii binary":    {[]byte("BSII\x03\x00\x00\x00"), TypeSII},
+		"dds":           {[]byte("DDS |\x00\x00\x00"), TypeDDS},
+		"pmg":           {[]byte("\x15Gmp\x00\x00"), TypePMG},
+		"pmd":           {[]byte{0x04, 0, 0, 0, 0x01, 0, 0, 0}, TypePMD},
+		"pma":           {[]byte{0x03, 0, 0, 0, 0x10, 0, 0, 0}, TypePMA},
+		"pmc":           {[]byte{0x06, 0, 0, 0, 0x02, 0, 0, 0}, TypePMC},
+		"ogg":           {[]byte("OggS\x00\x02"), TypeOGG},
+		"bank":          {[]byte("RIFF\x10\x00\x00\x00FEV FMT "), TypeBank},
+		"tobj":          {[]byte{0x01, 0x0a, 0xb1, 0x70, 0, 0}, TypeTOBJ},
+		"mat":           {[]byte("material : \"eut2.dif\" {\n texture : \"icon.tobj\"\n}\n"), TypeMAT},
+		"font":          {[]byte("# SCS Font\nvert_span:20\nline_spacing:4\n"), TypeFont},
+		"lua":           {[]byte("-- script\nfunction init()\nend\n"), TypeLua},
+		"text":          {[]byte("just some notes\n"), TypeText},
+		"binary":        {[]byte{0xff, 0x00, 0x12, 0x34, 0x56}, TypeUnknown},
+		"empty":         {nil, TypeUnknown},
+	} {
+		if got := Sniff(tc.data); got != tc.expect {
+			t.Errorf("%s: expected %s, got %s", name, tc.expect, got)
+		}
+	}
+
+	if ext := TypeTOBJ.Extension(); ext != ".tobj" {
+		t.Errorf("unexpected extension %q", ext)
+	}
+}
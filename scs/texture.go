@@ -0,0 +1,194 @@
+package scs
+
+import "fmt"
+
+// Texture sampler filters
+const (
+	FilterNearest TextureFilter = iota
+	FilterLinear
+	FilterNone
+)
+
+// Texture sampler address (wrap) modes
+const (
+	AddressRepeat TextureAddress = iota
+	AddressClamp
+	AddressClampToEdge
+	AddressClampToBorder
+	AddressMirror
+	AddressMirrorClamp
+	AddressMirrorClampToEdge
+)
+
+type (
+	// TextureFilter is the filter used when sampling a texture
+	TextureFilter uint8
+
+	// TextureAddress is the mode used to address texture coordinates
+	// outside the texture
+	TextureAddress uint8
+
+	// Sampler contains the sampler settings stored along with an
+	// image entry of a HashFS v2 archive
+	Sampler struct {
+		MagFilter TextureFilter
+		MinFilter TextureFilter
+		MipFilter TextureFilter
+		AddressU  TextureAddress
+		AddressV  TextureAddress
+		AddressW  TextureAddress
+	}
+
+	// TextureInfo describes an image entry of a HashFS v2 archive
+	TextureInfo struct {
+		Width  uint32
+		Height uint32
+		Format DXGIFormat
+		// MipCount is the number of mip levels of every face
+		MipCount uint32
+		IsCube   bool
+		// ArraySize is the number of textures (or cube maps) stored
+		ArraySize uint32
+		// Faces is the total number of images stored (6 per cube map)
+		Faces          uint32
+		PitchAlignment uint32
+		ImageAlignment uint32
+		// Sampler is nil if the entry has no sampler settings
+		Sampler *Sampler
+	}
+
+	// MipLayout describes how the mip levels of an image entry are
+	// stored within a HashFS v2 archive
+	MipLayout struct {
+		// MipCount is the number of mip levels of every face
+		MipCount uint32
+		// Faces is the number of images stored (6 per cube map)
+		Faces uint32
+		// Chunks lists the data chunks in the order of their mip levels
+		Chunks []MipChunk
+		// Proxy is the hash of the entry holding the data for mip proxy
+		// entries or zero if the data is stored with the image
+		Proxy uint64
+	}
+
+	// MipChunk is a single data chunk containing a range of mip levels
+	// of all faces of an image
+	MipChunk struct {
+		FirstMip       uint32
+		MipCount       uint32
+		CompressedSize uint32
+		IsCompressed   bool
+
+		chunk metaChunk
+	}
+)
+
+var (
+	textureFilterNames = map[TextureFilter]string{
+		FilterNearest: "nearest",
+		FilterLinear:  "linear",
+		FilterNone:    "none",
+	}
+
+	textureAddressNames = map[TextureAddress]string{
+		AddressRepeat:            "repeat",
+		AddressClamp:             "clamp",
+		AddressClampToEdge:       "clamp_to_edge",
+		AddressClampToBorder:     "clamp_to_border",
+		AddressMirror:            "mirror",
+		AddressMirrorClamp:       "mirror_clamp",
+		AddressMirrorClampToEdge: "mirror_clamp_to_edge",
+	}
+)
+
+// newSampler decodes the sample metadata word: one bit each for the
+// mag and min filter, two bits mip filter and three bits for each
+// address mode
+//
+//nolint:mnd // Bitfield decoding
+func newSampler(w uint32) *Sampler {
+	return &Sampler{
+		MagFilter: TextureFilter(w & 0x1),
+		MinFilter: TextureFilter((w >> 1) & 0x1),
+		MipFilter: TextureFilter((w >> 2) & 0x3),
+		AddressU:  TextureAddress((w >> 4) & 0x7),
+		AddressV:  TextureAddress((w >> 7) & 0x7),
+		AddressW:  TextureAddress((w >> 10) & 0x7),
+	}
+}
+
+// newMipLayout assigns the mip levels to the data chunks of the
+// image: mip 0 and mip 1 might be stored separately, the tail contains
+// all levels not stored in a previous chunk
+func newMipLayout(image *imageMeta, mip0, mip1, tail *metaChunk) *MipLayout {
+	l := &MipLayout{MipCount: image.mipCount, Faces: image.imageCount}
+
+	add := func(first, count uint32, c *metaChunk) {
+		l.Chunks = append(l.Chunks, MipChunk{
+			FirstMip:       first,
+			MipCount:       count,
+			CompressedSize: c.CompressedSize,
+			IsCompressed:   c.Flags&metaFlagCompressed != 0,
+			chunk:          *c,
+		})
+	}
+
+	var next uint32
+	if mip0 != nil {
+		add(0, 1, mip0)
+		next = 1
+	}
+
+	if mip1 != nil {
+		add(1, 1, mip1)
+		next = 2 //nolint:mnd
+	}
+
+	if tail != nil && image.mipCount > next {
+		add(next, image.mipCount-next, tail)
+	}
+
+	return l
+}
+
+// TextureInfo returns the description of the image stored in the
+// entry without decoding its pixel data. For files not being a HashFS
+// v2 image entry nil is returned.
+func (f *File) TextureInfo() *TextureInfo {
+	if f.image == nil {
+		return nil
+	}
+
+	info := &TextureInfo{
+		Width:          f.image.width,
+		Height:         f.image.height,
+		Format:         f.image.format,
+		MipCount:       f.image.mipCount,
+		IsCube:         f.image.isCube,
+		ArraySize:      f.image.imageCount,
+		Faces:          f.image.imageCount,
+		PitchAlignment: f.image.pitchAlignment,
+		ImageAlignment: f.image.imageAlignment,
+		Sampler:        f.Sampler,
+	}
+
+	if info.IsCube {
+		info.ArraySize = max(1, info.Faces/cubeFaces)
+	}
+
+	return info
+}
+
+func (f TextureFilter) String() string {
+	if name, ok := textureFilterNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown_%d", f)
+}
+
+func (a TextureAddress) String() string {
+	if name, ok := textureAddressNames[a]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown_%d", a)
+}
@@ -0,0 +1,136 @@
+package scs
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// splitMipArchive creates a Reader for an 8x8 BC1 texture with 3
+// mips: 32 byte mip 0 (compressed from the given data), 8 byte mip 1
+// and 8 byte mip tail, and a mip proxy referencing it
+func splitMipArchive(t *testing.T, mip0Data []byte) *Reader {
+	t.Helper()
+
+	mip0, err := zlibCompress(mip0Data)
+	if err != nil {
+		t.Fatalf("compressing mip 0: %s", err)
+	}
+
+	data := make([]byte, 0x60)
+	copy(data, mip0)
+	copy(data[0x40:], bytes.Repeat([]byte{0x2}, 8))
+	copy(data[0x50:], bytes.Repeat([]byte{0x3}, 8))
+
+	hdr := func(idx uint32, t catalogMetaEntryType) uint32 { return metaHeader{Index: idx, Type: t}.word() }
+
+	words := []uint32{
+		// headers of the image entry
+		hdr(7, metaEntryTypeImage), hdr(9, metaEntryTypeSample),
+		hdr(10, metaEntryTypeMip0), hdr(14, metaEntryTypeMip1), hdr(18, metaEntryTypeMipTail),
+		// headers of the proxy entry
+		hdr(22, metaEntryTypeImage), hdr(24, metaEntryTypeMipProxy),
+		// image: 8x8, 3 mips, BC1
+		7 | 7<<16, 2 | 71<<4,
+		// sampler: linear mag, nearest min, no mip filter, clamp U, mirror V
+		1 | 2<<2 | 1<<4 | 4<<7,
+	}
+	words = append(words, metaChunk{CompressedSize: uint32(len(mip0)), Flags: metaFlagCompressed, Size: 32}.words()...) //#nosec:G115 // Test data is small
+	words = append(words, metaChunk{CompressedSize: 8, Size: 8, Offset: 0x40}.words()...)
+	words = append(words, metaChunk{CompressedSize: 8, Size: 8, Offset: 0x50}.words()...)
+	words = append(words, 7|7<<16, 2|71<<4, 0x1234, 0)
+
+	entries := []catalogEntry{
+		{Hash: 0x1234, MetadataIndex: 0, MetadataCount: 5},
+		{Hash: 0x5678, MetadataIndex: 5, MetadataCount: 2},
+	}
+
+	r := &Reader{archiveReader: bytes.NewReader(data), metaTable: newMetaTable(words, entries)}
+	for _, e := range entries {
+		f, err := r.fileFromMetadata(e)
+		if err != nil {
+			t.Fatalf("decoding entry %016x: %s", e.Hash, err)
+		}
+		r.Files = append(r.Files, f)
+	}
+
+	if err = r.resolveMipProxies(); err != nil {
+		t.Fatalf("resolving proxies: %s", err)
+	}
+
+	return r
+}
+
+func TestSplitMipImage(t *testing.T) {
+	r := splitMipArchive(t, bytes.Repeat([]byte{0x1}, 32)) //nolint:mnd
+
+	img, proxied := r.Files[0], r.Files[1]
+
+	expectSampler := Sampler{MagFilter: FilterLinear, MinFilter: FilterNearest, MipFilter: FilterNone, AddressU: AddressClamp, AddressV: AddressMirror}
+	if img.Sampler == nil || *img.Sampler != expectSampler {
+		t.Errorf("unexpected sampler: %+v", img.Sampler)
+	}
+
+	if img.Mips.MipCount != 3 || img.Mips.Faces != 1 || len(img.Mips.Chunks) != 3 {
+		t.Fatalf("unexpected mip layout: %+v", img.Mips)
+	}
+
+	if tail := img.Mips.Chunks[2]; tail.FirstMip != 2 || tail.MipCount != 1 || tail.IsCompressed {
+		t.Errorf("unexpected mip tail: %+v", tail)
+	}
+
+	if proxied.Mips.Proxy != 0x1234 || len(proxied.Mips.Chunks) != 3 {
+		t.Errorf("unexpected proxy layout: %+v", proxied.Mips)
+	}
+
+	for _, f := range []*File{img, proxied} {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("opening image: %s", err)
+		}
+
+		dds, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatalf("reading image: %s", err)
+		}
+
+		if uint32(len(dds)) != f.Size { //#nosec:G115 // Test data is small
+			t.Errorf("unexpected DDS size: expect=%d result=%d", f.Size, len(dds))
+		}
+
+		expect := append(append(bytes.Repeat([]byte{0x1}, 32), bytes.Repeat([]byte{0x2}, 8)...), bytes.Repeat([]byte{0x3}, 8)...) //nolint:mnd
+		if !bytes.Equal(dds[len(ddsMagic)+ddsHeaderSize:], expect) {
+			t.Errorf("unexpected pixel data: %x", dds[len(ddsMagic)+ddsHeaderSize:])
+		}
+	}
+
+	if problems := r.Verify(); len(problems) > 0 {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+}
+
+func TestTextureInfo(t *testing.T) {
+	if info := (&File{}).TextureInfo(); info != nil {
+		t.Errorf("expected no texture info for plain file, got %+v", info)
+	}
+
+	// 256x128 BC7 cube map with 10 mips
+	f := &File{image: newImageMeta(255, 127, 9|98<<4|1<<12|5<<14), Sampler: newSampler(1 | 1<<1)}
+
+	info := f.TextureInfo()
+	if info.Width != 256 || info.Height != 128 || info.MipCount != 10 {
+		t.Errorf("unexpected dimensions: %dx%d, %d mips", info.Width, info.Height, info.MipCount)
+	}
+
+	if info.Format.String() != "BC7_UNORM" || !info.IsCube || info.Faces != 6 || info.ArraySize != 1 {
+		t.Errorf("unexpected texture info: %+v", info)
+	}
+
+	if info.Sampler.MinFilter != FilterLinear || info.Sampler.AddressU != AddressRepeat {
+		t.Errorf("unexpected sampler: %+v", info.Sampler)
+	}
+
+	if name := DXGIFormat(1000).String(); name != "DXGIFormat(1000)" {
+		t.Errorf("unexpected name of unknown format: %s", name)
+	}
+}
@@ -0,0 +1,115 @@
+package scs
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	v1FlagIsDirectory  = 0x1
+	v1FlagIsCompressed = 0x2
+
+	v1DirPrefix = "*"
+)
+
+type (
+	// fileHeaderV1 is the header used in HashFS v1 archives (game
+	// versions prior to 1.50)
+	fileHeaderV1 struct {
+		Magic           [4]byte
+		Version         uint16
+		Salt            uint16
+		HashMethod      [4]byte
+		EntryCount      uint32
+		EntryTableStart uint32
+	}
+
+	// catalogEntryV1 is the flat entry format used in HashFS v1
+	// archives which does not have a separate metadata table
+	catalogEntryV1 struct {
+		Hash           uint64
+		Offset         uint64
+		Flags          uint32
+		CRC            uint32
+		Size           uint32
+		CompressedSize uint32
+	}
+)
+
+func (r *Reader) parseV1() (err error) {
+	var header fileHeaderV1
+	if err = binary.Read(
+		io.NewSectionReader(r.archiveReader, 0, int64(binary.Size(fileHeaderV1{}))),
+		binary.LittleEndian,
+		&header,
+	); err != nil {
+		return fmt.Errorf("reading header: %w", err)
+	}
+
+	etReader := io.NewSectionReader(
+		r.archiveReader,
+		int64(header.EntryTableStart),
+		int64(header.EntryCount)*int64(binary.Size(catalogEntryV1{})),
+	)
+
+	for i := uint32(0); i < header.EntryCount; i++ {
+		var e catalogEntryV1
+		if err = binary.Read(etReader, binary.LittleEndian, &e); err != nil {
+			return fmt.Errorf("reading entry: %w", err)
+		}
+
+		r.Files = append(r.Files, &File{
+			CompressedSize: e.CompressedSize,
+			Hash:           e.Hash,
+			IsCompressed:   e.Flags&v1FlagIsCompressed != 0,
+			IsDirectory:    e.Flags&v1FlagIsDirectory != 0,
+			Size:           e.Size,
+			archiveReader:  r.archiveReader,
+			offset:         e.Offset,
+		})
+	}
+
+	return nil
+}
+
+// readDirListingV1 parses the textual directory listing of HashFS v1
+// archives: one entry per line, directories prefixed with an asterisk
+func (*Reader) readDirListingV1(node *File) ([]dirListingEntry, error) {
+	f, err := node.Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening file: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	var (
+		entries []dirListingEntry
+		scanner = bufio.NewScanner(f)
+	)
+
+	for scanner.Scan() {
+		name := scanner.Text()
+		if name == "" {
+			continue
+		}
+
+		if strings.HasPrefix(name, v1DirPrefix) {
+			entries = append(entries, dirListingEntry{Name: strings.TrimPrefix(name, v1DirPrefix), IsDirectory: true})
+			continue
+		}
+
+		entries = append(entries, dirListingEntry{Name: name})
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading listing: %w", err)
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no entries in directory listing")
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,115 @@
+package scs
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Luzifer/scs-extract/b0rkhash"
+)
+
+type v1TestFile struct {
+	name     string
+	content  string
+	flags    uint32
+	compress bool
+}
+
+// writeV1Archive builds a HashFS v1 archive containing the given
+// entries, directories need their listing given as content
+func writeV1Archive(t *testing.T, files []v1TestFile) []byte {
+	t.Helper()
+
+	var (
+		data    = new(bytes.Buffer)
+		entries []catalogEntryV1
+		hdrSize = binary.Size(fileHeaderV1{})
+	)
+
+	data.Write(make([]byte, hdrSize))
+
+	for _, f := range files {
+		payload := []byte(f.content)
+		if f.compress {
+			buf := new(bytes.Buffer)
+			zw := zlib.NewWriter(buf)
+			zw.Write(payload) //nolint:errcheck,gosec
+			zw.Close()        //nolint:errcheck,gosec
+			payload = buf.Bytes()
+			f.flags |= v1FlagIsCompressed
+		}
+
+		entries = append(entries, catalogEntryV1{
+			Hash:           b0rkhash.CityHash64([]byte(f.name)),
+			Offset:         uint64(data.Len()),
+			Flags:          f.flags,
+			Size:           uint32(len(f.content)),
+			CompressedSize: uint32(len(payload)),
+		})
+		data.Write(payload)
+	}
+
+	hdr := fileHeaderV1{
+		Version:         archiveVersion1,
+		EntryCount:      uint32(len(entries)),
+		EntryTableStart: uint32(data.Len()),
+	}
+	copy(hdr.Magic[:], scsMagic)
+	copy(hdr.HashMethod[:], scsHashMethod)
+
+	if err := binary.Write(data, binary.LittleEndian, entries); err != nil {
+		t.Fatalf("writing entries: %s", err)
+	}
+
+	archive := data.Bytes()
+	hdrBuf := new(bytes.Buffer)
+	if err := binary.Write(hdrBuf, binary.LittleEndian, hdr); err != nil {
+		t.Fatalf("writing header: %s", err)
+	}
+	copy(archive, hdrBuf.Bytes())
+
+	return archive
+}
+
+func TestReadV1Archive(t *testing.T) {
+	files := []v1TestFile{
+		{name: "", content: "*def\nreadme.txt\n", flags: v1FlagIsDirectory},
+		{name: "def", content: "a.sii\n", flags: v1FlagIsDirectory, compress: true},
+		{name: "def/a.sii", content: "SiiNunit\n{\n}\n", compress: true},
+		{name: "readme.txt", content: "Hello World"},
+	}
+
+	archive := writeV1Archive(t, files)
+
+	r, err := NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("opening archive: %s", err)
+	}
+
+	if f := r.Format(); f != "HashFS v1" {
+		t.Errorf("unexpected format %q", f)
+	}
+
+	for _, f := range files[2:] {
+		fr, err := r.Open(f.name)
+		if err != nil {
+			t.Fatalf("opening %s: %s", f.name, err)
+		}
+
+		content, err := io.ReadAll(fr)
+		if err != nil {
+			t.Fatalf("reading %s: %s", f.name, err)
+		}
+
+		if string(content) != f.content {
+			t.Errorf("unexpected content in %s: %q", f.name, content)
+		}
+	}
+
+	if err = fstest.TestFS(r, "def/a.sii", "readme.txt"); err != nil {
+		t.Errorf("filesystem test failed: %s", err)
+	}
+}
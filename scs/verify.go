@@ -0,0 +1,211 @@
+package scs
+
+import (
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Problem describes an integrity issue found by Verify
+type Problem struct {
+	Name string
+	Hash uint64
+	Err  error
+}
+
+func (p Problem) Error() string {
+	return fmt.Sprintf("%s: %s", p.displayName(), p.Err)
+}
+
+func (p Problem) Unwrap() error { return p.Err }
+
+// displayName returns the name of the affected file or its hash for
+// files without known name
+func (p Problem) displayName() string {
+	if p.Name == "" && p.Hash == 0 {
+		return "<archive>"
+	}
+
+	if p.Name == "" {
+		return fmt.Sprintf("<%016x>", p.Hash)
+	}
+	return p.Name
+}
+
+// Verify checks the integrity of all files within the archive without
+// extracting them: data of every file must be located within the
+// archive and must not overlap the data of other files, compressed
+// data must have a valid zlib header and checksum and every file must
+// decompress to its recorded size. All problems found are returned,
+// an intact archive yields no problems.
+func (r *Reader) Verify() (problems []Problem) {
+	problems = append(problems, r.verifyLayout()...)
+
+	for _, f := range r.Files {
+		if err := f.verify(); err != nil {
+			problems = append(problems, Problem{Name: f.Name, Hash: f.Hash, Err: err})
+		}
+	}
+
+	return problems
+}
+
+// dataChunks returns the locations of the file data within the
+// archive: images might be split into multiple mip chunks
+func (f *File) dataChunks() []metaChunk {
+	if f.Mips == nil {
+		return []metaChunk{f.chunk()}
+	}
+
+	chunks := make([]metaChunk, len(f.Mips.Chunks))
+	for i, c := range f.Mips.Chunks {
+		chunks[i] = c.chunk
+	}
+	return chunks
+}
+
+// storedSize returns the number of bytes the chunk of the file
+// occupies within the archive
+func (f *File) storedSize(c metaChunk) uint32 {
+	if c.Flags&metaFlagCompressed != 0 || f.image != nil {
+		return c.CompressedSize
+	}
+	return c.Size
+}
+
+// hasArchiveData reports whether the file data is stored within the
+// HashFS archive (and not served by ZIP or directory readers)
+func (f *File) hasArchiveData() bool {
+	return f.open == nil && f.archiveReader != nil
+}
+
+func (f *File) verify() error {
+	switch {
+	case f.open != nil:
+		// ZIP entries are validated by the CRC checks of archive/zip
+		// and directory files only need to be readable
+		return f.verifyLength(f.Open, f.Size)
+
+	case f.archiveReader == nil:
+		// Virtual entry without data
+		return nil
+	}
+
+	for _, c := range f.dataChunks() {
+		if err := f.verifyChunk(c); err != nil {
+			if f.Mips != nil && len(f.Mips.Chunks) > 1 {
+				return fmt.Errorf("chunk at %d: %w", c.Offset, err)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f *File) verifyChunk(c metaChunk) error {
+	if c.Flags&metaFlagCompressed == 0 {
+		size := f.storedSize(c)
+		return f.verifyLength(func() (io.ReadCloser, error) { return f.openChunk(c, size), nil }, size)
+	}
+
+	// Read the stored data including the zlib header and checksum
+	// which are skipped when opening the file
+	zr, err := zlib.NewReader(io.NewSectionReader(f.archiveReader, int64(c.Offset), int64(c.CompressedSize))) //#nosec:G115 // int64 wraps at 9EB - We don't have to care for a LONG time
+	if err != nil {
+		return fmt.Errorf("invalid zlib header: %w", err)
+	}
+	defer zr.Close() //nolint:errcheck
+
+	n, err := io.Copy(io.Discard, zr)
+	if err != nil {
+		return fmt.Errorf("decompressing: %w", err)
+	}
+
+	if n != int64(c.Size) {
+		return fmt.Errorf("decompressed to %d byte, expected %d byte", n, c.Size)
+	}
+
+	return nil
+}
+
+func (*File) verifyLength(open func() (io.ReadCloser, error), size uint32) error {
+	rc, err := open()
+	if err != nil {
+		return fmt.Errorf("opening file: %w", err)
+	}
+	defer rc.Close() //nolint:errcheck
+
+	n, err := io.Copy(io.Discard, rc)
+	if err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+
+	if n != int64(size) {
+		return fmt.Errorf("read %d byte, expected %d byte", n, size)
+	}
+
+	return nil
+}
+
+// verifyLayout checks the data of all files lies within the archive
+// and the data of no two files overlaps. Files sharing the exact same
+// data (deduplicated by the packer or referenced through a mip proxy)
+// are allowed.
+func (r *Reader) verifyLayout() (problems []Problem) {
+	type storedData struct {
+		file   *File
+		offset uint64
+		size   uint32
+	}
+
+	var stored []storedData
+	for _, f := range r.Files {
+		if !f.hasArchiveData() {
+			continue
+		}
+
+		for _, c := range f.dataChunks() {
+			if size := f.storedSize(c); size > 0 {
+				stored = append(stored, storedData{f, c.Offset, size})
+			}
+		}
+	}
+
+	if len(stored) == 0 {
+		return nil
+	}
+
+	size, err := readerSize(r.archiveReader)
+	if err != nil {
+		return []Problem{{Err: fmt.Errorf("determining archive size: %w", err)}}
+	}
+
+	sort.Slice(stored, func(i, j int) bool { return stored[i].offset < stored[j].offset })
+
+	for i, d := range stored {
+		end := d.offset + uint64(d.size)
+		if end > uint64(size) { //#nosec:G115 // Sizes are never negative
+			problems = append(problems, Problem{Name: d.file.Name, Hash: d.file.Hash, Err: fmt.Errorf(
+				"data at %d-%d exceeds archive size %d", d.offset, end, size,
+			)})
+		}
+
+		for _, next := range stored[i+1:] {
+			if next.offset >= end {
+				break
+			}
+
+			if next.offset == d.offset && next.size == d.size {
+				continue
+			}
+
+			problems = append(problems, Problem{Name: d.file.Name, Hash: d.file.Hash, Err: fmt.Errorf(
+				"data at %d-%d overlaps data of %s", d.offset, end, Problem{Name: next.file.Name, Hash: next.file.Hash}.displayName(),
+			)})
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,129 @@
+package scs
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	archive := filepath.Join(t.TempDir(), "test.scs")
+	f, err := os.Create(archive) //#nosec:G304 // Test file
+	if err != nil {
+		t.Fatalf("creating archive: %s", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	w, err := NewWriter(f)
+	if err != nil {
+		t.Fatalf("creating writer: %s", err)
+	}
+
+	for name, content := range files {
+		if err = w.AddFile(name, strings.NewReader(content)); err != nil {
+			t.Fatalf("adding %s: %s", name, err)
+		}
+	}
+
+	if err = w.Close(); err != nil {
+		t.Fatalf("closing writer: %s", err)
+	}
+
+	data, err := os.ReadFile(archive) //#nosec:G304 // Test file
+	if err != nil {
+		t.Fatalf("reading archive: %s", err)
+	}
+
+	return data
+}
+
+func TestVerify(t *testing.T) {
+	data := writeTestArchive(t, map[string]string{
+		"def/city.sii":    strings.Repeat("city_data: .berlin\n", 100),
+		"def/company.sii": strings.Repeat("company_data: .acme\n", 100),
+		"manifest.sii":    "short",
+	})
+
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("reading archive: %s", err)
+	}
+
+	if problems := r.Verify(); len(problems) > 0 {
+		t.Fatalf("intact archive reported problems: %v", problems)
+	}
+
+	// Corrupt the Adler32 checksum at the end of the compressed data
+	city, err := r.lookup("verify", "def/city.sii")
+	if err != nil {
+		t.Fatalf("looking up file: %s", err)
+	}
+	data[city.offset+uint64(city.CompressedSize)-1] ^= 0xff
+
+	if r, err = NewReader(bytes.NewReader(data)); err != nil {
+		t.Fatalf("reading archive: %s", err)
+	}
+
+	problems := r.Verify()
+	if len(problems) != 1 || problems[0].Name != "def/city.sii" || !strings.Contains(problems[0].Error(), "checksum") {
+		t.Errorf("unexpected problems for corrupted checksum: %v", problems)
+	}
+}
+
+func TestVerifyLayout(t *testing.T) {
+	archive := bytes.NewReader(make([]byte, 256))
+	r := &Reader{
+		archiveReader: archive,
+		Files: []*File{
+			{Name: "a", Size: 64, archiveReader: archive, offset: 64},
+			{Name: "b", Size: 64, archiveReader: archive, offset: 96},
+			{Name: "dedup", Size: 64, archiveReader: archive, offset: 160},
+			{Name: "dedup2", Size: 64, archiveReader: archive, offset: 160},
+			{Name: "c", Size: 64, archiveReader: archive, offset: 240},
+		},
+	}
+
+	var got []string
+	for _, p := range r.Verify() {
+		got = append(got, p.Error())
+	}
+
+	for _, expect := range []string{
+		"a: data at 64-128 overlaps data of b",
+		"c: data at 240-304 exceeds archive size 256",
+		"c: read 16 byte, expected 64 byte",
+	} {
+		found := false
+		for _, g := range got {
+			found = found || g == expect
+		}
+
+		if !found {
+			t.Errorf("expected problem %q, got %v", expect, got)
+		}
+	}
+
+	if len(got) != 3 { //nolint:mnd
+		t.Errorf("unexpected number of problems: %v", got)
+	}
+}
+
+func TestVerifyShortImageChunk(t *testing.T) {
+	// Mip 0 is recorded with 32 byte but only 24 byte are stored
+	r := splitMipArchive(t, bytes.Repeat([]byte{0x1}, 24)) //nolint:mnd
+
+	problems := r.Verify()
+	if len(problems) != 2 { //nolint:mnd // Image and its proxy
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+
+	for _, p := range problems {
+		if !strings.Contains(p.Error(), "decompressed to 24 byte, expected 32 byte") {
+			t.Errorf("unexpected problem: %s", p)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package scs
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"path"
+	"slices"
+	"sort"
+
+	"github.com/Luzifer/scs-extract/b0rkhash"
+)
+
+const (
+	// maxCompressedSize is the largest size storable in the 28 bit
+	// size field of the metadata entries
+	maxCompressedSize = 1<<28 - 1
+	maxNameLength     = math.MaxUint8
+
+	metaEntryHeaderSize = 1 // uint32 words
+	metaEntryFileSize   = metaChunkWords
+
+	writerDataStart = 0x40
+)
+
+type (
+	// Writer creates HashFS v2 archives readable by NewReader and the
+	// game. Files are added using AddFile or AddFS, the directory
+	// listings and tables are written when calling Close.
+	Writer struct {
+		w      io.WriteSeeker
+		offset uint64
+
+		entries map[string]*writerEntry
+		// dirs contains the directories derived from the added files
+		dirs   map[string]bool
+		closed bool
+	}
+
+	writerEntry struct {
+		name           string
+		isDir          bool
+		offset         uint64
+		size           uint32
+		compressedSize uint32
+		compressed     bool
+	}
+)
+
+var (
+	// ErrWriterClosed signals the Writer was already closed
+	ErrWriterClosed = errors.New("writer already closed")
+
+	// ErrEmptyArchive signals Close was called without adding any
+	// file: archives without entries cannot be read
+	ErrEmptyArchive = errors.New("no files added to archive")
+)
+
+// NewWriter creates a Writer writing the archive to w. As the header
+// references the tables at the end of the archive, w needs to be
+// seekable.
+func NewWriter(w io.WriteSeeker) (*Writer, error) {
+	// Reserve space for the header
+	if _, err := w.Write(make([]byte, writerDataStart)); err != nil {
+		return nil, fmt.Errorf("reserving header space: %w", err)
+	}
+
+	return &Writer{
+		w:       w,
+		offset:  writerDataStart,
+		entries: make(map[string]*writerEntry),
+		dirs:    make(map[string]bool),
+	}, nil
+}
+
+// AddFile reads the content for the named file from r and adds it to
+// the archive. The content is compressed when this saves space.
+func (w *Writer) AddFile(name string, r io.Reader) error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+
+	if !fs.ValidPath(name) || name == "." {
+		return fmt.Errorf("invalid file name %q", name)
+	}
+
+	if _, ok := w.entries[name]; ok {
+		return fmt.Errorf("duplicate file %q", name)
+	}
+
+	// Files and directories share their names, a file must neither
+	// replace a directory nor be the parent of another file
+	if w.dirs[name] {
+		return fmt.Errorf("file %q collides with directory of the same name", name)
+	}
+
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if _, ok := w.entries[dir]; ok {
+			return fmt.Errorf("directory of file %q collides with file %q", name, dir)
+		}
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("reading content of %s: %w", name, err)
+	}
+
+	e, err := w.writeData(name, data)
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+
+	w.entries[name] = e
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		w.dirs[dir] = true
+	}
+
+	return nil
+}
+
+// AddFS adds all regular files found in the given filesystem (for
+// example an os.DirFS of the mod directory) to the archive
+func (w *Writer) AddFS(fsys fs.FS) error {
+	return w.AddFSExcept(fsys)
+}
+
+// AddFSExcept works like AddFS but skips the files with the given
+// names (i.e. the archive being written into the same directory)
+func (w *Writer) AddFSExcept(fsys fs.FS, exclude ...string) error {
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error { //nolint:wrapcheck
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() || slices.Contains(exclude, p) {
+			// Directories are derived from file names, everything else
+			// cannot be stored in the archive
+			return nil
+		}
+
+		f, err := fsys.Open(p)
+		if err != nil {
+			return fmt.Errorf("opening %s: %w", p, err)
+		}
+		defer f.Close() //nolint:errcheck
+
+		return w.AddFile(p, f)
+	})
+}
+
+// Close writes the directory listings, the entry- and metadata-table
+// and the header. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+	w.closed = true
+
+	if len(w.entries) == 0 {
+		return ErrEmptyArchive
+	}
+
+	if err := w.writeDirListings(); err != nil {
+		return fmt.Errorf("writing directory listings: %w", err)
+	}
+
+	var (
+		entries  = make([]catalogEntry, 0, len(w.entries))
+		metadata = new(bytes.Buffer)
+		sorted   = make([]*writerEntry, 0, len(w.entries))
+	)
+
+	for _, e := range w.entries {
+		sorted = append(sorted, e)
+	}
+
+	// The game looks up entries by hash so the table must be sorted
+	sort.Slice(sorted, func(i, j int) bool {
+		return b0rkhash.CityHash64([]byte(sorted[i].name)) < b0rkhash.CityHash64([]byte(sorted[j].name))
+	})
+
+	for i, e := range sorted {
+		metaIndex := uint32(i * (metaEntryHeaderSize + metaEntryFileSize)) //#nosec:G115 // Entry count is limited by uint32 header field
+		entries = append(entries, catalogEntry{
+			Hash:          b0rkhash.CityHash64([]byte(e.name)),
+			MetadataIndex: metaIndex,
+			MetadataCount: metaEntryHeaderSize,
+		})
+
+		if err := e.writeMetadata(metadata, metaIndex+metaEntryHeaderSize); err != nil {
+			return fmt.Errorf("writing metadata for %s: %w", e.name, err)
+		}
+	}
+
+	entryTable := new(bytes.Buffer)
+	if err := binary.Write(entryTable, binary.LittleEndian, entries); err != nil {
+		return fmt.Errorf("encoding entry table: %w", err)
+	}
+
+	hdr := fileHeader{
+		Version:              archiveVersion2,
+		EntryCount:           uint32(len(entries)),                                             //#nosec:G115 // Would not fit into the header otherwise
+		MetadataEntriesCount: uint32(len(entries) * (metaEntryHeaderSize + metaEntryFileSize)), //#nosec:G115 // Would not fit into the header otherwise
+	}
+	copy(hdr.Magic[:], scsMagic)
+	copy(hdr.HashMethod[:], scsHashMethod)
+
+	var err error
+	if hdr.EntryTableStart, hdr.EntryTableLength, err = w.writeTable(entryTable.Bytes()); err != nil {
+		return fmt.Errorf("writing entry table: %w", err)
+	}
+
+	if hdr.MetadataTableStart, hdr.MetadataTableLength, err = w.writeTable(metadata.Bytes()); err != nil {
+		return fmt.Errorf("writing metadata table: %w", err)
+	}
+
+	if _, err = w.w.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("seeking to header: %w", err)
+	}
+
+	if err = binary.Write(w.w, binary.LittleEndian, hdr); err != nil {
+		return fmt.Errorf("writing header: %w", err)
+	}
+
+	if _, err = w.w.Seek(0, io.SeekEnd); err != nil {
+		return fmt.Errorf("seeking to end: %w", err)
+	}
+
+	return nil
+}
+
+// writeData compresses the given data if this saves space and writes
+// it aligned to the offset block size
+func (w *Writer) writeData(name string, data []byte) (*writerEntry, error) {
+	if uint64(len(data)) > math.MaxUint32 {
+		return nil, fmt.Errorf("file too large")
+	}
+
+	e := &writerEntry{
+		name:   name,
+		offset: w.offset,
+		size:   uint32(len(data)),
+	}
+
+	payload := data
+	compressed, err := zlibCompress(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(compressed) < len(data) {
+		payload = compressed
+		e.compressed = true
+	}
+
+	if len(payload) > maxCompressedSize {
+		return nil, fmt.Errorf("file too large")
+	}
+	e.compressedSize = uint32(len(payload)) //#nosec:G115 // Checked above
+
+	padding := (offsetBlockSize - len(payload)%offsetBlockSize) % offsetBlockSize
+	if _, err = w.w.Write(append(payload, make([]byte, padding)...)); err != nil {
+		return nil, fmt.Errorf("writing data: %w", err)
+	}
+	w.offset += uint64(len(payload) + padding) //#nosec:G115 // Never negative
+
+	return e, nil
+}
+
+// writeDirListings creates the listings for all directories (including
+// the root directory) derived from the names of the added files
+func (w *Writer) writeDirListings() error {
+	children := map[string]map[string]bool{"": {}}
+
+	for name := range w.entries {
+		for child := name; child != ""; {
+			parent := path.Dir(child)
+			if parent == "." {
+				parent = ""
+			}
+
+			entry := path.Base(child)
+			if child != name {
+				entry = "/" + entry
+			}
+
+			if children[parent] == nil {
+				children[parent] = make(map[string]bool)
+			}
+			children[parent][entry] = true
+			child = parent
+		}
+	}
+
+	dirs := make([]string, 0, len(children))
+	for dir := range children {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		names := make([]string, 0, len(children[dir]))
+		for name := range children[dir] {
+			if len(name) > maxNameLength {
+				return fmt.Errorf("name too long: %s", name)
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		listing := new(bytes.Buffer)
+		binary.Write(listing, binary.LittleEndian, uint32(len(names))) //nolint:errcheck,gosec // Writing to buffer
+		for _, name := range names {
+			listing.WriteByte(byte(len(name)))
+		}
+		for _, name := range names {
+			listing.WriteString(name)
+		}
+
+		e, err := w.writeData(dir, listing.Bytes())
+		if err != nil {
+			return fmt.Errorf("writing listing for %q: %w", dir, err)
+		}
+
+		e.isDir = true
+		w.entries[dir] = e
+	}
+
+	return nil
+}
+
+func (w *Writer) writeTable(data []byte) (start uint64, length uint32, err error) {
+	compressed, err := zlibCompress(data)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start = w.offset
+	if _, err = w.w.Write(compressed); err != nil {
+		return 0, 0, fmt.Errorf("writing table: %w", err)
+	}
+	w.offset += uint64(len(compressed))
+
+	return start, uint32(len(compressed)), nil //#nosec:G115 // Tables will not exceed 4GB
+}
+
+// writeMetadata writes the metadata header followed by the plain- or
+// directory-entry referenced by the header
+func (e writerEntry) writeMetadata(w io.Writer, index uint32) error {
+	hdr := metaHeader{Index: index, Type: metaEntryTypePlain}
+	if e.isDir {
+		hdr.Type = metaEntryTypeDirectory
+	}
+
+	chunk := metaChunk{
+		CompressedSize: e.compressedSize,
+		Size:           e.size,
+		Offset:         e.offset,
+	}
+	if e.compressed {
+		chunk.Flags = metaFlagCompressed
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, append([]uint32{hdr.word()}, chunk.words()...)); err != nil {
+		return fmt.Errorf("writing metadata: %w", err)
+	}
+
+	return nil
+}
+
+func zlibCompress(data []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	zw := zlib.NewWriter(buf)
+
+	if _, err := zw.Write(data); err != nil {
+		return nil, fmt.Errorf("compressing: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("closing compressor: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,220 @@
+package scs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWriterRoundTrip(t *testing.T) {
+	files := map[string]string{
+		"manifest.sii":         "SiiNunit { }",
+		"def/city.sii":         strings.Repeat("city_data: .berlin\n", 100),
+		"def/country/de.sii":   "short",
+		"material/ui/icon.mat": "",
+	}
+
+	archive := filepath.Join(t.TempDir(), "test.scs")
+	f, err := os.Create(archive) //#nosec:G304 // Test file
+	if err != nil {
+		t.Fatalf("creating archive: %s", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	w, err := NewWriter(f)
+	if err != nil {
+		t.Fatalf("creating writer: %s", err)
+	}
+
+	if err = w.AddFS(os.DirFS(writeTestTree(t, files))); err != nil {
+		t.Fatalf("adding files: %s", err)
+	}
+
+	if err = w.Close(); err != nil {
+		t.Fatalf("closing writer: %s", err)
+	}
+
+	if err = w.AddFile("late.txt", strings.NewReader("")); !errors.Is(err, ErrWriterClosed) {
+		t.Errorf("expected ErrWriterClosed, got %v", err)
+	}
+
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("seeking archive: %s", err)
+	}
+
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("reading archive: %s", err)
+	}
+
+	for name, expect := range files {
+		data, err := r.ReadFile(name)
+		if err != nil {
+			t.Fatalf("reading %s: %s", name, err)
+		}
+
+		if string(data) != expect {
+			t.Errorf("unexpected content for %s: %q", name, data)
+		}
+	}
+
+	for _, f := range r.Files {
+		expectType := "plain"
+		if f.IsDirectory {
+			expectType = "directory"
+		}
+
+		if f.MetadataType() != expectType {
+			t.Errorf("unexpected metadata type %q for %q", f.MetadataType(), f.Name)
+		}
+
+		if f.Offset() < writerDataStart || f.Offset()%offsetBlockSize != 0 {
+			t.Errorf("unexpected offset %d for %q", f.Offset(), f.Name)
+		}
+	}
+
+	if err = fstest.TestFS(r, "manifest.sii", "def/city.sii", "def/country/de.sii", "material/ui/icon.mat"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWriterRejectsInvalidNames(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.scs"))
+	if err != nil {
+		t.Fatalf("creating archive: %s", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	w, err := NewWriter(f)
+	if err != nil {
+		t.Fatalf("creating writer: %s", err)
+	}
+
+	for _, name := range []string{"", ".", "../escape", "/abs", "a//b"} {
+		if err = w.AddFile(name, bytes.NewReader(nil)); err == nil {
+			t.Errorf("expected error for name %q", name)
+		}
+	}
+
+	if err = w.AddFile("a.txt", bytes.NewReader(nil)); err != nil {
+		t.Fatalf("adding file: %s", err)
+	}
+
+	if err = w.AddFile("a.txt", bytes.NewReader(nil)); err == nil {
+		t.Error("expected error for duplicate file")
+	}
+}
+
+func TestWriterRejectsEmptyArchive(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.scs"))
+	if err != nil {
+		t.Fatalf("creating archive: %s", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	w, err := NewWriter(f)
+	if err != nil {
+		t.Fatalf("creating writer: %s", err)
+	}
+
+	if err = w.AddFS(fstest.MapFS{}); err != nil {
+		t.Fatalf("adding empty filesystem: %s", err)
+	}
+
+	if err = w.Close(); !errors.Is(err, ErrEmptyArchive) {
+		t.Errorf("expected empty archive error, got %v", err)
+	}
+}
+
+func TestWriterRejectsNameCollisions(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.scs"))
+	if err != nil {
+		t.Fatalf("creating archive: %s", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	w, err := NewWriter(f)
+	if err != nil {
+		t.Fatalf("creating writer: %s", err)
+	}
+
+	for _, name := range []string{"a", "def/city.sii"} {
+		if err = w.AddFile(name, strings.NewReader(name)); err != nil {
+			t.Fatalf("adding %s: %s", name, err)
+		}
+	}
+
+	// Files below an existing file and files named like an existing
+	// directory would overwrite each other in the listings
+	for _, name := range []string{"a/b", "a/b/c", "def"} {
+		if err = w.AddFile(name, bytes.NewReader(nil)); err == nil {
+			t.Errorf("expected error for %q", name)
+		}
+	}
+
+	if err = w.Close(); err != nil {
+		t.Fatalf("closing writer: %s", err)
+	}
+
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("seeking archive: %s", err)
+	}
+
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("reading archive: %s", err)
+	}
+
+	if err = fstest.TestFS(r, "a", "def/city.sii"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWriterAddFSExcept(t *testing.T) {
+	src := writeTestTree(t, map[string]string{
+		"def/city.sii": "city",
+		"mod.scs":      "half-written archive",
+	})
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.scs"))
+	if err != nil {
+		t.Fatalf("creating archive: %s", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	w, err := NewWriter(f)
+	if err != nil {
+		t.Fatalf("creating writer: %s", err)
+	}
+
+	if err = w.AddFSExcept(os.DirFS(src), "mod.scs"); err != nil {
+		t.Fatalf("adding files: %s", err)
+	}
+
+	if err = w.Close(); err != nil {
+		t.Fatalf("closing writer: %s", err)
+	}
+
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("seeking archive: %s", err)
+	}
+
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("reading archive: %s", err)
+	}
+
+	if _, err = r.Stat("mod.scs"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected excluded file to be missing, got %v", err)
+	}
+
+	if err = fstest.TestFS(r, "def/city.sii"); err != nil {
+		t.Error(err)
+	}
+}
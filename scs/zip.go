@@ -0,0 +1,88 @@
+package scs
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"path"
+	"strings"
+
+	"github.com/Luzifer/scs-extract/b0rkhash"
+)
+
+var zipMagic = []byte("PK\x03\x04")
+
+// parseZip reads mod archives which are ZIP files using the .scs
+// extension and presents their contents the same way as the contents
+// of a HashFS archive
+func (r *Reader) parseZip() error {
+	size, err := readerSize(r.archiveReader)
+	if err != nil {
+		return fmt.Errorf("determining archive size: %w", err)
+	}
+
+	zr, err := zip.NewReader(r.archiveReader, size)
+	if err != nil {
+		return fmt.Errorf("opening zip reader: %w", err)
+	}
+
+	r.initVirtualRoot()
+
+	for _, zf := range zr.File {
+		name := strings.Trim(path.Clean("/"+strings.ReplaceAll(zf.Name, `\`, "/")), "/")
+		if name == "" {
+			continue
+		}
+
+		if zf.FileInfo().IsDir() {
+			r.virtualDir(name)
+			continue
+		}
+
+		if _, ok := r.byName[name]; ok {
+			// Duplicate entries are possible in ZIP files, the first one wins
+			continue
+		}
+
+		r.addVirtualFile(&File{
+			Name:           name,
+			CompressedSize: clampUint32(zf.CompressedSize64),
+			Hash:           b0rkhash.CityHash64([]byte(name)),
+			IsCompressed:   zf.Method != zip.Store,
+			Size:           clampUint32(zf.UncompressedSize64),
+			open:           zf.Open,
+		})
+	}
+
+	return nil
+}
+
+func clampUint32(v uint64) uint32 {
+	if v > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(v)
+}
+
+// readerSize tries to determine the size of the archive as it is
+// required to read the ZIP central directory
+func readerSize(r io.ReaderAt) (int64, error) {
+	switch v := r.(type) {
+	case interface{ Size() int64 }:
+		return v.Size(), nil
+
+	case interface{ Stat() (fs.FileInfo, error) }:
+		info, err := v.Stat()
+		if err != nil {
+			return 0, fmt.Errorf("getting file info: %w", err)
+		}
+		return info.Size(), nil
+
+	case io.Seeker:
+		return v.Seek(0, io.SeekEnd) //nolint:wrapcheck
+	}
+
+	return 0, fmt.Errorf("reader does not expose its size")
+}
@@ -0,0 +1,76 @@
+package scs
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadZipArchive(t *testing.T) {
+	files := map[string]string{
+		"def/vehicle/truck.sii": "SiiNunit\n{\n}\n",
+		"manifest.sii":          "SiiNunit\n{\nmod_package : .package_name {\n}\n}\n",
+	}
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for name, content := range files {
+		method := zip.Deflate
+		if name == "manifest.sii" {
+			method = zip.Store
+		}
+
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			t.Fatalf("creating zip entry: %s", err)
+		}
+
+		if _, err = w.Write([]byte(content)); err != nil {
+			t.Fatalf("writing zip entry: %s", err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatalf("closing zip writer: %s", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("opening archive: %s", err)
+	}
+
+	if f := r.Format(); f != "ZIP" {
+		t.Errorf("unexpected format %q", f)
+	}
+
+	for name, content := range files {
+		data, err := r.ReadFile(name)
+		if err != nil {
+			t.Fatalf("reading %s: %s", name, err)
+		}
+
+		if string(data) != content {
+			t.Errorf("unexpected content in %s: %q", name, data)
+		}
+	}
+
+	var dirs []string
+	for _, f := range r.Files {
+		if f.IsDirectory {
+			dirs = append(dirs, f.Name)
+		}
+
+		if f.Name == "manifest.sii" && f.IsCompressed {
+			t.Errorf("stored file reported as compressed")
+		}
+	}
+
+	if len(dirs) != 3 { //nolint:mnd // root, def, def/vehicle
+		t.Errorf("unexpected directories: %q", dirs)
+	}
+
+	if err = fstest.TestFS(r, "def/vehicle/truck.sii", "manifest.sii"); err != nil {
+		t.Errorf("filesystem test failed: %s", err)
+	}
+}
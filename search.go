@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/Luzifer/scs-extract/scs"
+	"github.com/Luzifer/scs-extract/sii"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// binarySniffSize is the number of bytes checked for NUL bytes to
+	// skip binary files
+	binarySniffSize = 512
+	// maxLineLength limits the length of lines searched in
+	maxLineLength = 1024 * 1024
+)
+
+// searchArchive prints the lines of the selected files matching the
+// regular expression as "name:line:text"
+func searchArchive(archive, expr string, patterns []string) (err error) {
+	if cfg.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("parsing expression: %w", err)
+	}
+
+	selector, err := buildSelector(patterns)
+	if err != nil {
+		return fmt.Errorf("parsing file selection: %w", err)
+	}
+
+	files, archiveCloser, err := openArchive(archive)
+	if err != nil {
+		return fmt.Errorf("opening archive: %w", err)
+	}
+	defer archiveCloser.Close() //nolint:errcheck
+
+	out := bufio.NewWriter(os.Stdout)
+	defer func() {
+		if ferr := out.Flush(); ferr != nil && err == nil {
+			err = fmt.Errorf("flushing output: %w", ferr)
+		}
+	}()
+
+	for _, f := range selector.Filter(files) {
+		if f.IsDirectory || f.Name == "" {
+			// Matches in entries without name could not be attributed
+			continue
+		}
+
+		if err = searchFile(out, re, f); err != nil {
+			// Keep searching the other files
+			logrus.WithField("file", f.Name).WithError(err).Warn("searching file")
+		}
+	}
+
+	return nil
+}
+
+// searchFile writes the matching lines of the file, binary files
+// (containing NUL bytes in the first bytes) are skipped
+func searchFile(w io.Writer, re *regexp.Regexp, f *scs.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("opening file: %w", err)
+	}
+	defer rc.Close() //nolint:errcheck
+
+	var content io.Reader = rc
+	if cfg.DecodeSII {
+		if content, err = sii.NewDecoder(rc); err != nil {
+			return fmt.Errorf("decoding file: %w", err)
+		}
+	}
+
+	br := bufio.NewReader(content)
+	if head, _ := br.Peek(binarySniffSize); bytes.IndexByte(head, 0) >= 0 {
+		return nil
+	}
+
+	s := bufio.NewScanner(br)
+	s.Buffer(nil, maxLineLength)
+	for line := 1; s.Scan(); line++ {
+		if !re.Match(s.Bytes()) {
+			continue
+		}
+
+		if cfg.FilesWithMatches {
+			_, err = fmt.Fprintln(w, f.Name)
+			return err //nolint:wrapcheck
+		}
+
+		if _, err = fmt.Fprintf(w, "%s:%d:%s\n", f.Name, line, s.Bytes()); err != nil {
+			return fmt.Errorf("writing match: %w", err)
+		}
+	}
+
+	if err = s.Err(); err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package sii
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// NoIndex is used as Attribute.Index for attributes which are not
+// addressing a specific array element
+const NoIndex = -1
+
+const hexFloatPrefix = "&"
+
+type (
+	// Document represents a parsed SiiNunit file
+	Document struct {
+		Units []*Unit
+
+		// Includes contains the @include directives found outside of
+		// units which were not resolved while parsing
+		Includes []Include
+	}
+
+	// Unit is a single unit ("class : name { ... }") within a Document
+	Unit struct {
+		Class      string
+		Name       string
+		Attributes []Attribute
+
+		// Includes contains the @include directives found inside the
+		// unit which were not resolved while parsing
+		Includes []Include
+
+		File string
+		Line int
+	}
+
+	// Attribute is a single "key: value" line inside an Unit
+	Attribute struct {
+		Key string
+		// Index is the explicit array index given as "key[1]" or
+		// NoIndex if no index was given
+		Index int
+		// Append is set for attributes given as "key[]" which are
+		// appended to the array named by Key
+		Append bool
+
+		Value Value
+	}
+
+	// Include is an @include directive referencing another file
+	Include struct {
+		Path string
+		File string
+		Line int
+	}
+
+	// Value represents any of the attribute values: String, Token,
+	// Number, Vector or Placement
+	Value interface {
+		fmt.Stringer
+		isValue()
+	}
+
+	// String is a quoted string value
+	String string
+
+	// Token is an unquoted value like a token, a link to another unit,
+	// a boolean or "nil"
+	Token string
+
+	// Number is a numeric value stored in its textual representation
+	// to avoid losing precision. Floats might be given as "&" followed
+	// by the hex representation of the IEEE 754 value.
+	Number string
+
+	// Vector is a tuple of numbers like "(1, 2, 3)"
+	Vector []Number
+
+	// Placement is a position and a quaternion rotation given as
+	// "(x, y, z) (w; x, y, z)"
+	Placement struct {
+		Position Vector
+		Rotation Vector
+	}
+)
+
+func (String) isValue()    {}
+func (Token) isValue()     {}
+func (Number) isValue()    {}
+func (Vector) isValue()    {}
+func (Placement) isValue() {}
+
+// String returns the quoted representation of the string
+func (s String) String() string { return strconv.Quote(string(s)) }
+
+// String returns the token itself
+func (t Token) String() string { return string(t) }
+
+// String returns the textual representation of the number
+func (n Number) String() string { return string(n) }
+
+// Float64 parses the number as floating point value
+func (n Number) Float64() (float64, error) {
+	if strings.HasPrefix(string(n), hexFloatPrefix) {
+		bits, err := strconv.ParseUint(strings.TrimPrefix(string(n), hexFloatPrefix), 16, 32)
+		if err != nil {
+			return 0, fmt.Errorf("parsing hex float: %w", err)
+		}
+		return float64(math.Float32frombits(uint32(bits))), nil
+	}
+
+	v, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing float: %w", err)
+	}
+	return v, nil
+}
+
+// Int64 parses the number as signed integer
+func (n Number) Int64() (int64, error) {
+	v, err := strconv.ParseInt(string(n), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing int: %w", err)
+	}
+	return v, nil
+}
+
+// Uint64 parses the number as unsigned integer
+func (n Number) Uint64() (uint64, error) {
+	v, err := strconv.ParseUint(string(n), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing uint: %w", err)
+	}
+	return v, nil
+}
+
+// String returns the tuple notation of the vector
+func (v Vector) String() string {
+	parts := make([]string, len(v))
+	for i := range v {
+		parts[i] = string(v[i])
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// String returns the placement notation "(x, y, z) (w; x, y, z)"
+func (p Placement) String() string {
+	rot := p.Rotation.String()
+	if len(p.Rotation) > 1 {
+		rot = "(" + string(p.Rotation[0]) + "; " + strings.TrimPrefix(Vector(p.Rotation[1:]).String(), "(")
+	}
+	return p.Position.String() + " " + rot
+}
+
+// Unit returns the first unit with the given name or nil if there
+// is no such unit
+func (d *Document) Unit(name string) *Unit {
+	for _, u := range d.Units {
+		if u.Name == name {
+			return u
+		}
+	}
+	return nil
+}
+
+// UnitsByClass returns all units of the given class
+func (d *Document) UnitsByClass(class string) (units []*Unit) {
+	for _, u := range d.Units {
+		if u.Class == class {
+			units = append(units, u)
+		}
+	}
+	return units
+}
+
+// Get returns the value of the last non-array attribute with the
+// given key or nil if the attribute is not present
+func (u *Unit) Get(key string) Value {
+	var v Value
+	for _, a := range u.Attributes {
+		if a.Key == key && a.Index == NoIndex && !a.Append {
+			v = a.Value
+		}
+	}
+	return v
+}
+
+// Array assembles the array stored in the given key from the "key[]"
+// and "key[n]" attributes of the unit
+func (u *Unit) Array(key string) []Value {
+	var out []Value
+	for _, a := range u.Attributes {
+		switch {
+		case a.Key != key:
+			continue
+
+		case a.Append:
+			out = append(out, a.Value)
+
+		case a.Index != NoIndex:
+			for len(out) <= a.Index {
+				out = append(out, nil)
+			}
+			out[a.Index] = a.Value
+		}
+	}
+	return out
+}
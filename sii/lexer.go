@@ -0,0 +1,232 @@
+package sii
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenString
+	tokenWord
+)
+
+const (
+	punctChars = "{}:(),;"
+	utf8BOM    = "\xef\xbb\xbf"
+)
+
+type (
+	tokenKind int
+
+	token struct {
+		kind tokenKind
+		text string
+		file string
+		line int
+	}
+
+	lexer struct {
+		r    *bufio.Reader
+		file string
+		line int
+	}
+)
+
+func newLexer(r io.Reader, file string) *lexer {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(len(utf8BOM)); err == nil && string(bom) == utf8BOM {
+		br.Discard(len(utf8BOM)) //nolint:errcheck,gosec // Peeked before, cannot fail
+	}
+
+	return &lexer{r: br, file: file, line: 1}
+}
+
+func (t token) is(kind tokenKind, text string) bool {
+	return t.kind == kind && t.text == text
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of file"
+	case tokenString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// next returns the next token skipping whitespace and comments
+//
+//nolint:gocyclo // Lexer state machine is easier to read in one piece
+func (l *lexer) next() (token, error) {
+	for {
+		c, err := l.readByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return token{kind: tokenEOF, file: l.file, line: l.line}, nil
+			}
+			return token{}, err
+		}
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			continue
+
+		case c == '#':
+			if err = l.skipLine(); err != nil {
+				return token{}, err
+			}
+			continue
+
+		case c == '/' && l.peekByte() == '/':
+			if err = l.skipLine(); err != nil {
+				return token{}, err
+			}
+			continue
+
+		case c == '/' && l.peekByte() == '*':
+			if err = l.skipBlockComment(); err != nil {
+				return token{}, err
+			}
+			continue
+
+		case c == '"':
+			return l.readString()
+
+		case strings.IndexByte(punctChars, c) >= 0:
+			return token{kind: tokenPunct, text: string(c), file: l.file, line: l.line}, nil
+
+		default:
+			return l.readWord(c)
+		}
+	}
+}
+
+func (l *lexer) peekByte() byte {
+	b, err := l.r.Peek(1)
+	if err != nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (l *lexer) readByte() (byte, error) {
+	c, err := l.r.ReadByte()
+	if err != nil {
+		return 0, err //nolint:wrapcheck // Handled by caller
+	}
+
+	if c == '\n' {
+		l.line++
+	}
+
+	return c, nil
+}
+
+func (l *lexer) readString() (token, error) {
+	var (
+		buf  strings.Builder
+		line = l.line
+	)
+
+	for {
+		c, err := l.readByte()
+		if err != nil {
+			return token{}, &ParseError{File: l.file, Line: line, Err: errors.New("unterminated string")}
+		}
+
+		switch c {
+		case '"':
+			return token{kind: tokenString, text: buf.String(), file: l.file, line: line}, nil
+
+		case '\\':
+			if c, err = l.readByte(); err != nil {
+				return token{}, &ParseError{File: l.file, Line: line, Err: errors.New("unterminated string")}
+			}
+
+			switch c {
+			case 'n':
+				buf.WriteByte('\n')
+			case 't':
+				buf.WriteByte('\t')
+			case 'x':
+				hex := make([]byte, 2) //nolint:mnd
+				if _, err = io.ReadFull(l.r, hex); err != nil {
+					return token{}, &ParseError{File: l.file, Line: line, Err: errors.New("unterminated escape sequence")}
+				}
+
+				v, err := strconv.ParseUint(string(hex), 16, 8)
+				if err != nil {
+					return token{}, &ParseError{File: l.file, Line: line, Err: fmt.Errorf("invalid escape sequence: %w", err)}
+				}
+				buf.WriteByte(byte(v))
+			default:
+				buf.WriteByte(c)
+			}
+
+		default:
+			buf.WriteByte(c)
+		}
+	}
+}
+
+func (l *lexer) readWord(first byte) (token, error) {
+	buf := []byte{first}
+
+	for {
+		c := l.peekByte()
+		if c == 0 || c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '"' || strings.IndexByte(punctChars, c) >= 0 {
+			return token{kind: tokenWord, text: string(buf), file: l.file, line: l.line}, nil
+		}
+
+		if _, err := l.readByte(); err != nil {
+			return token{}, err
+		}
+		buf = append(buf, c)
+	}
+}
+
+func (l *lexer) skipBlockComment() error {
+	line := l.line
+
+	// Consume the asterisk of the opening sequence
+	if _, err := l.readByte(); err != nil {
+		return &ParseError{File: l.file, Line: line, Err: errors.New("unterminated comment")}
+	}
+
+	var prev byte
+	for {
+		c, err := l.readByte()
+		if err != nil {
+			return &ParseError{File: l.file, Line: line, Err: errors.New("unterminated comment")}
+		}
+
+		if prev == '*' && c == '/' {
+			return nil
+		}
+		prev = c
+	}
+}
+
+func (l *lexer) skipLine() error {
+	for {
+		c, err := l.readByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		if c == '\n' {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package sii
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	includeDirective = "@include"
+	unitFileMagic    = "SiiNunit"
+)
+
+type (
+	// ParseError describes a problem in the parsed file including the
+	// position the problem was found at
+	ParseError struct {
+		File string
+		Line int
+		Err  error
+	}
+
+	// includeFunc resolves the include path found in the given file
+	// and returns the name and content of the included file
+	includeFunc func(from Include) (name string, r io.Reader, err error)
+
+	parser struct {
+		lexers []*lexer
+		// includes holds the directives the inputs following the
+		// first one in lexers were included by
+		includes []Include
+		peeked   *token
+		include  includeFunc
+	}
+)
+
+func (e *ParseError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// Parse reads a SiiNunit file from the given reader. Encrypted and
+// binary files are decoded transparently. As there is no filesystem
+// to resolve @include directives from, they are collected in the
+// Includes fields of the Document and its Units.
+func Parse(r io.Reader) (*Document, error) {
+	dr, err := NewDecoder(r)
+	if err != nil {
+		return nil, fmt.Errorf("decoding file: %w", err)
+	}
+
+	p := &parser{lexers: []*lexer{newLexer(dr, "")}}
+	return p.parseDocument()
+}
+
+// ParseFS reads the named SiiNunit file from the given filesystem (for
+// example a *scs.Reader) and resolves @include directives against the
+// same filesystem. See Resolver for details.
+func ParseFS(fsys fs.FS, name string) (*Document, error) {
+	return NewResolver(fsys).Parse(name)
+}
+
+// ResolveIncludePath returns the path of the file referenced by an
+// @include directive inside the file at from. Absolute include paths
+// start at the root of the filesystem, relative ones are resolved
+// from the directory of the including file.
+func ResolveIncludePath(from, include string) string {
+	if strings.HasPrefix(include, "/") {
+		return strings.TrimPrefix(path.Clean(include), "/")
+	}
+
+	return strings.TrimPrefix(path.Join(path.Dir(from), include), "/")
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return &ParseError{File: t.file, Line: t.line, Err: fmt.Errorf(format, args...)}
+}
+
+func (p *parser) expect(kind tokenKind, text string) (token, error) {
+	t, err := p.next()
+	if err != nil {
+		return t, err
+	}
+
+	if !t.is(kind, text) {
+		return t, p.errorf(t, "expected %q, found %s", text, t)
+	}
+
+	return t, nil
+}
+
+// handleInclude reads the path of the @include directive and either
+// switches the input to the included file or records the directive
+// if no include resolver is available
+func (p *parser) handleInclude(directive token, unresolved *[]Include) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+
+	if t.kind != tokenString {
+		return p.errorf(t, "expected include path, found %s", t)
+	}
+
+	inc := Include{Path: t.text, File: directive.file, Line: directive.line}
+	if p.include == nil {
+		*unresolved = append(*unresolved, inc)
+		return nil
+	}
+
+	name, r, err := p.include(inc)
+	if err != nil {
+		return p.errorf(directive, "including %q: %w", inc.Path, err)
+	}
+
+	p.lexers = append(p.lexers, newLexer(r, name))
+	p.includes = append(p.includes, inc)
+	return nil
+}
+
+// next returns the next token of the current input. The end of an
+// included file is returned as tokenEOF, only nextStatement continues
+// with the including file.
+func (p *parser) next() (token, error) {
+	if p.peeked != nil {
+		t := *p.peeked
+		p.peeked = nil
+		return t, nil
+	}
+
+	return p.lexers[len(p.lexers)-1].next()
+}
+
+// nextStatement returns the next token at the start of a statement
+// within a construct opened while depth inputs were active. Included
+// files ending there are left for the including file, everywhere else
+// their end is reported as unexpected end of file.
+func (p *parser) nextStatement(depth int) (token, error) {
+	for {
+		t, err := p.next()
+		if err != nil {
+			return t, err
+		}
+
+		if t.kind == tokenEOF && len(p.lexers) > depth {
+			p.lexers = p.lexers[:len(p.lexers)-1]
+			p.includes = p.includes[:len(p.includes)-1]
+			continue
+		}
+
+		return t, nil
+	}
+}
+
+// wrapIncludes adds the @include directives leading to the currently
+// read input to an error raised while reading it
+func (p *parser) wrapIncludes(err error) error {
+	for i := len(p.includes) - 1; i >= 0; i-- {
+		inc := p.includes[i]
+		err = &ParseError{File: inc.File, Line: inc.Line, Err: fmt.Errorf("including %q: %w", inc.Path, err)}
+	}
+	return err
+}
+
+func (p *parser) peek() (token, error) {
+	if p.peeked != nil {
+		return *p.peeked, nil
+	}
+
+	t, err := p.next()
+	if err != nil {
+		return t, err
+	}
+
+	p.peeked = &t
+	return t, nil
+}
+
+func (p *parser) parseAttribute(key token, u *Unit) error {
+	if _, err := p.expect(tokenPunct, ":"); err != nil {
+		return err
+	}
+
+	attr := Attribute{Key: key.text, Index: NoIndex}
+	if open := strings.IndexByte(key.text, '['); open >= 0 {
+		if !strings.HasSuffix(key.text, "]") {
+			return p.errorf(key, "invalid attribute key %q", key.text)
+		}
+
+		attr.Key = key.text[:open]
+		idx := key.text[open+1 : len(key.text)-1]
+
+		if idx == "" {
+			attr.Append = true
+		} else {
+			i, err := strconv.Atoi(idx)
+			if err != nil || i < 0 {
+				return p.errorf(key, "invalid array index %q", idx)
+			}
+			attr.Index = i
+		}
+	}
+
+	v, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+
+	attr.Value = v
+	u.Attributes = append(u.Attributes, attr)
+
+	return nil
+}
+
+func (p *parser) parseDocument() (*Document, error) {
+	doc, err := p.parseUnits()
+	if err != nil {
+		return nil, p.wrapIncludes(err)
+	}
+	return doc, nil
+}
+
+func (p *parser) parseUnits() (*Document, error) {
+	if _, err := p.expect(tokenWord, unitFileMagic); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.expect(tokenPunct, "{"); err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	for {
+		t, err := p.nextStatement(1)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case t.is(tokenPunct, "}") && len(p.lexers) > 1:
+			return nil, p.errorf(t, "unexpected %s closing the including file", t)
+
+		case t.is(tokenPunct, "}"):
+			return doc, nil
+
+		case t.is(tokenWord, includeDirective):
+			if err = p.handleInclude(t, &doc.Includes); err != nil {
+				return nil, err
+			}
+
+		case t.kind == tokenWord:
+			u, err := p.parseUnit(t)
+			if err != nil {
+				return nil, err
+			}
+			doc.Units = append(doc.Units, u)
+
+		default:
+			return nil, p.errorf(t, "expected unit, found %s", t)
+		}
+	}
+}
+
+func (p *parser) parseTuple(open token) (Vector, error) {
+	var v Vector
+	for {
+		t, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		if t.kind != tokenWord || !isNumber(t.text) {
+			return nil, p.errorf(t, "expected number in tuple, found %s", t)
+		}
+		v = append(v, Number(t.text))
+
+		if t, err = p.next(); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case t.is(tokenPunct, ")"):
+			return v, nil
+
+		case t.is(tokenPunct, ","), t.is(tokenPunct, ";"):
+			continue
+
+		default:
+			return nil, p.errorf(t, "unterminated tuple started in line %d, found %s", open.line, t)
+		}
+	}
+}
+
+func (p *parser) parseUnit(class token) (*Unit, error) {
+	if _, err := p.expect(tokenPunct, ":"); err != nil {
+		return nil, err
+	}
+
+	name, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	if name.kind != tokenWord {
+		return nil, p.errorf(name, "expected unit name, found %s", name)
+	}
+
+	if _, err = p.expect(tokenPunct, "{"); err != nil {
+		return nil, err
+	}
+
+	var (
+		u     = &Unit{Class: class.text, Name: name.text, File: class.file, Line: class.line}
+		depth = len(p.lexers)
+	)
+	for {
+		t, err := p.nextStatement(depth)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case t.is(tokenPunct, "}") && len(p.lexers) > depth:
+			return nil, p.errorf(t, "unexpected %s closing unit %s started in %s line %d", t, u.Name, u.File, u.Line)
+
+		case t.is(tokenPunct, "}"):
+			return u, nil
+
+		case t.is(tokenWord, includeDirective):
+			if err = p.handleInclude(t, &u.Includes); err != nil {
+				return nil, err
+			}
+
+		case t.kind == tokenWord:
+			if err = p.parseAttribute(t, u); err != nil {
+				return nil, err
+			}
+
+		default:
+			return nil, p.errorf(t, "expected attribute in unit %s, found %s", u.Name, t)
+		}
+	}
+}
+
+func (p *parser) parseValue() (Value, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case t.kind == tokenString:
+		return String(t.text), nil
+
+	case t.is(tokenPunct, "("):
+		pos, err := p.parseTuple(t)
+		if err != nil {
+			return nil, err
+		}
+
+		next, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+
+		if !next.is(tokenPunct, "(") {
+			return pos, nil
+		}
+
+		p.peeked = nil
+		rot, err := p.parseTuple(next)
+		if err != nil {
+			return nil, err
+		}
+
+		return Placement{Position: pos, Rotation: rot}, nil
+
+	case t.kind == tokenWord && isNumber(t.text):
+		return Number(t.text), nil
+
+	case t.kind == tokenWord:
+		return Token(t.text), nil
+
+	default:
+		return nil, p.errorf(t, "expected value, found %s", t)
+	}
+}
+
+func isNumber(s string) bool {
+	if strings.HasPrefix(s, hexFloatPrefix) {
+		_, err := strconv.ParseUint(s[len(hexFloatPrefix):], 16, 32)
+		return err == nil
+	}
+
+	if s == "" || !strings.ContainsRune("0123456789+-.", rune(s[0])) {
+		return false
+	}
+
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
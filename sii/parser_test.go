@@ -0,0 +1,207 @@
+package sii
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const testDocument = `SiiNunit
+{
+# Comment
+company_def : company.volvo.def // trailing comment
+{
+	name: "Volvo \"Trucks\""
+	sort: 3
+	price: &3f000000
+	rating: -1.5
+	active: true
+	city: .city.berlin
+	cargo[]: .cargo.a
+	cargo[]: .cargo.b
+	ratio[0]: 1
+	ratio[1]: 2
+	pos: (1, 2, &3f800000)
+	placement: (1, 2, 3) (1; 0, 0, 0)
+	/* block
+	   comment */
+	@include "extra.sui"
+}
+
+@include "/def/units.sui"
+}
+`
+
+func TestParse(t *testing.T) {
+	doc, err := Parse(strings.NewReader(testDocument))
+	if err != nil {
+		t.Fatalf("parsing document: %s", err)
+	}
+
+	if len(doc.Units) != 1 || len(doc.Includes) != 1 || doc.Includes[0].Path != "/def/units.sui" {
+		t.Fatalf("unexpected document structure: %+v", doc)
+	}
+
+	u := doc.Unit("company.volvo.def")
+	if u == nil || u.Class != "company_def" || u.Line != 4 {
+		t.Fatalf("unit not found or invalid: %+v", u)
+	}
+
+	if len(u.Includes) != 1 || u.Includes[0].Path != "extra.sui" || u.Includes[0].Line != 20 {
+		t.Errorf("unexpected unit includes: %+v", u.Includes)
+	}
+
+	for key, expect := range map[string]Value{
+		"name":      String(`Volvo "Trucks"`),
+		"sort":      Number("3"),
+		"price":     Number("&3f000000"),
+		"rating":    Number("-1.5"),
+		"active":    Token("true"),
+		"city":      Token(".city.berlin"),
+		"pos":       Vector{"1", "2", "&3f800000"},
+		"placement": Placement{Position: Vector{"1", "2", "3"}, Rotation: Vector{"1", "0", "0", "0"}},
+	} {
+		if v := u.Get(key); v == nil || v.String() != expect.String() {
+			t.Errorf("unexpected value for %s: %v", key, v)
+		}
+	}
+
+	if f, err := u.Get("price").(Number).Float64(); err != nil || f != 0.5 {
+		t.Errorf("unexpected hex float value: %v (%v)", f, err)
+	}
+
+	if arr := u.Array("cargo"); len(arr) != 2 || arr[1] != Token(".cargo.b") {
+		t.Errorf("unexpected cargo array: %v", arr)
+	}
+
+	if arr := u.Array("ratio"); len(arr) != 2 || arr[1] != Number("2") {
+		t.Errorf("unexpected ratio array: %v", arr)
+	}
+}
+
+func TestParseFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"def/company.sii": {Data: []byte(testDocument)},
+		"def/extra.sui":   {Data: []byte("\textra: 1\n")},
+		"def/units.sui":   {Data: []byte("cargo_def : cargo.a {\n}\n")},
+	}
+
+	doc, err := ParseFS(fsys, "def/company.sii")
+	if err != nil {
+		t.Fatalf("parsing document: %s", err)
+	}
+
+	if len(doc.Units) != 2 || doc.Units[1].File != "def/units.sui" {
+		t.Fatalf("included unit not found: %+v", doc.Units)
+	}
+
+	if v := doc.Units[0].Get("extra"); v != Number("1") {
+		t.Errorf("included attribute not found: %v", v)
+	}
+
+	delete(fsys, "def/extra.sui")
+	_, err = ParseFS(fsys, "def/company.sii")
+
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.File != "def/company.sii" || perr.Line != 20 {
+		t.Errorf("unexpected error for missing include: %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, input := range map[string]string{
+		"magic":        "SiiNunitX {\n}",
+		"unterminated": "SiiNunit {\nfoo : bar {\n a: 1\n",
+		"tuple":        "SiiNunit {\nfoo : bar {\n a: (1, x)\n}\n}",
+		"string":       "SiiNunit {\nfoo : bar {\n a: \"x\n}\n}",
+		"index":        "SiiNunit {\nfoo : bar {\n a[x]: 1\n}\n}",
+	} {
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("invalid input %q did not cause an error", name)
+		}
+	}
+}
+
+func TestResolverOverridesAndCycles(t *testing.T) {
+	base := fstest.MapFS{
+		"def/a.sii": {Data: []byte("SiiNunit {\n@include \"b.sui\"\n}\n")},
+		"def/b.sui": {Data: []byte("unit_a : a {\n}\n")},
+	}
+	mod := fstest.MapFS{
+		"def/b.sui": {Data: []byte("unit_b : b {\n}\n@include \"/def/c.sui\"\n")},
+		"def/c.sui": {Data: []byte("\n\n@include \"b.sui\"\n")},
+	}
+
+	_, err := NewResolver(base, mod).Parse("def/a.sii")
+	if !errors.Is(err, ErrIncludeCycle) {
+		t.Fatalf("expected include cycle, got: %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "def/c.sui:3") || !strings.Contains(err.Error(), "def/a.sii -> def/b.sui -> def/c.sui -> def/b.sui") {
+		t.Errorf("error does not contain context: %s", err)
+	}
+
+	delete(mod, "def/c.sui")
+	mod["def/b.sui"] = &fstest.MapFile{Data: []byte("unit_b : b {\n}\n")}
+
+	doc, err := NewResolver(base, mod).Parse("def/a.sii")
+	if err != nil {
+		t.Fatalf("parsing document: %s", err)
+	}
+
+	if len(doc.Units) != 1 || doc.Units[0].Name != "b" || len(doc.Includes) != 0 {
+		t.Errorf("override not applied: %+v", doc.Units)
+	}
+}
+
+func TestResolverIncludeErrors(t *testing.T) {
+	const (
+		unitLevel     = "SiiNunit {\nunit_a : a {\n@include \"b.sui\"\n}\n}\n"
+		documentLevel = "SiiNunit {\nunit_a : a {\n}\n@include \"b.sui\"\n}\n"
+	)
+
+	for name, tc := range map[string]struct {
+		including, included string
+		line                int
+		expect              string
+	}{
+		"syntax error": {
+			including: documentLevel, included: "unit_b : b {\n\tfoo bar\n}\n", line: 4,
+			expect: `def/a.sii:4: including "b.sui": def/b.sui:2: expected ":", found "bar"`,
+		},
+		"unterminated unit": {
+			including: documentLevel, included: "unit_b : b {\n\ta: 1\n", line: 4,
+			expect: `def/a.sii:4: including "b.sui": def/b.sui:3: expected attribute in unit b, found end of file`,
+		},
+		"unterminated tuple": {
+			including: unitLevel, included: "\tpos: (1, 2\n", line: 3,
+			expect: `def/a.sii:3: including "b.sui": def/b.sui:2: unterminated tuple started in line 1, found end of file`,
+		},
+		"unterminated attribute": {
+			including: unitLevel, included: "\tpos:\n", line: 3,
+			expect: `def/a.sii:3: including "b.sui": def/b.sui:2: expected value, found end of file`,
+		},
+		"closing including unit": {
+			including: unitLevel, included: "\ta: 1\n}\n", line: 3,
+			expect: `def/b.sui:2: unexpected "}" closing unit a started in def/a.sii line 2`,
+		},
+	} {
+		fsys := fstest.MapFS{
+			"def/a.sii": {Data: []byte(tc.including)},
+			"def/b.sui": {Data: []byte(tc.included)},
+		}
+
+		_, err := ParseFS(fsys, "def/a.sii")
+
+		var perr *ParseError
+		if !errors.As(err, &perr) || perr.File != "def/a.sii" || perr.Line != tc.line {
+			t.Errorf("%s: error does not name the including file: %v", name, err)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), tc.expect) {
+			t.Errorf("%s: error %q does not contain %q", name, err, tc.expect)
+		}
+	}
+}
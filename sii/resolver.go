@@ -0,0 +1,83 @@
+package sii
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// ErrIncludeCycle signals a file includes itself directly or through
+// other included files
+var ErrIncludeCycle = errors.New("include cycle")
+
+// Resolver parses SiiNunit files and recursively expands their
+// @include directives across a set of filesystems (for example the
+// *scs.Reader of every mounted archive). Sources are given in mount
+// order: when a file exists in multiple sources, the one added last
+// is used, the same way the game overrides files from earlier
+// archives.
+type Resolver struct {
+	sources []fs.FS
+}
+
+// NewResolver creates a Resolver reading from the given sources
+func NewResolver(sources ...fs.FS) *Resolver {
+	return &Resolver{sources: sources}
+}
+
+// Open returns the decoded content of the named file from the source
+// with the highest priority containing the file
+func (r *Resolver) Open(name string) (io.Reader, error) {
+	for i := len(r.sources) - 1; i >= 0; i-- {
+		data, err := fs.ReadFile(r.sources[i], name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", name, err)
+		}
+
+		return NewDecoder(bytes.NewReader(data))
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// Parse reads the named file and returns a flattened Document having
+// all @include directives replaced by the content of the included
+// files. Errors inside included files are reported as *ParseError
+// chain naming every including file and the line of the directive.
+func (r *Resolver) Parse(name string) (*Document, error) {
+	content, err := r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{lexers: []*lexer{newLexer(content, name)}}
+	p.include = func(inc Include) (string, io.Reader, error) {
+		target := ResolveIncludePath(inc.File, inc.Path)
+
+		var (
+			chain = make([]string, 0, len(p.lexers)+1)
+			cycle bool
+		)
+
+		for _, l := range p.lexers {
+			chain = append(chain, l.file)
+			cycle = cycle || l.file == target
+		}
+
+		if cycle {
+			return "", nil, fmt.Errorf("%w: %s", ErrIncludeCycle, strings.Join(append(chain, target), " -> "))
+		}
+
+		content, err := r.Open(target)
+		return target, content, err
+	}
+
+	return p.parseDocument()
+}
@@ -0,0 +1,135 @@
+// Package sii contains decoders and a parser for the SII unit files
+// used in Euro Truck Simulator 2 / American Truck Simulator
+package sii
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Luzifer/scs-extract/bsii"
+)
+
+type scscHeader struct {
+	Magic    [4]byte
+	HMAC     [32]byte
+	IV       [aes.BlockSize]byte
+	DataSize uint32
+}
+
+var (
+	scscMagic = []byte("ScsC")
+
+	// scscKey is the AES-256 key used by the game to encrypt SII files
+	scscKey = []byte{
+		0x2a, 0x5f, 0xcb, 0x17, 0x91, 0xd2, 0x2f, 0xb6, 0x02, 0x45, 0xb3, 0xd8, 0x36, 0x9e, 0xd0, 0xb2,
+		0xc2, 0x73, 0x71, 0x56, 0x3f, 0xbf, 0x1f, 0x3c, 0x9e, 0xdf, 0x6b, 0x11, 0x82, 0x5a, 0x5d, 0x0a,
+	}
+)
+
+// ErrInvalidData signals the data cannot be decoded as it is
+// malformed or truncated
+var ErrInvalidData = errors.New("invalid data")
+
+// IsEncrypted reports whether the given data starts with the ScsC
+// signature of an encrypted SII file
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, scscMagic)
+}
+
+// Decrypt decrypts and decompresses the given ScsC data
+func Decrypt(data []byte) ([]byte, error) {
+	var hdr scscHeader
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &hdr); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	if !bytes.Equal(hdr.Magic[:], scscMagic) {
+		return nil, fmt.Errorf("unexpected signature: %w", ErrInvalidData)
+	}
+
+	payload := data[binary.Size(hdr):]
+	if len(payload)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("payload is no multiple of block size: %w", ErrInvalidData)
+	}
+
+	block, err := aes.NewCipher(scscKey)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+
+	decrypted := make([]byte, len(payload))
+	cipher.NewCBCDecrypter(block, hdr.IV[:]).CryptBlocks(decrypted, payload)
+
+	zr, err := zlib.NewReader(bytes.NewReader(decrypted))
+	if err != nil {
+		return nil, fmt.Errorf("opening decompressor: %w", err)
+	}
+	defer zr.Close() //nolint:errcheck
+
+	plain, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing: %w", err)
+	}
+
+	if uint32(len(plain)) != hdr.DataSize { //#nosec:G115 // SII files will never exceed 4GB
+		return nil, fmt.Errorf("size mismatch (expected %d, got %d): %w", hdr.DataSize, len(plain), ErrInvalidData)
+	}
+
+	return plain, nil
+}
+
+// Decode returns the textual representation of the given SII data:
+// encrypted files are decrypted, binary (BSII) files are converted
+// into text and everything else is returned as-is
+func Decode(data []byte) (out []byte, err error) {
+	out = data
+
+	if IsEncrypted(out) {
+		if out, err = Decrypt(out); err != nil {
+			return nil, err
+		}
+	}
+
+	if bsii.IsBinary(out) {
+		if out, err = bsii.Decode(out); err != nil {
+			return nil, fmt.Errorf("decoding binary SII: %w", err)
+		}
+	}
+
+	return out, nil
+}
+
+// NewDecoder wraps the given reader and transparently decodes the
+// content in case it is an encrypted or binary SII file. Other
+// content is passed through without being buffered completely.
+func NewDecoder(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	sig, err := br.Peek(len(scscMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("reading signature: %w", err)
+	}
+
+	if !IsEncrypted(sig) && !bsii.IsBinary(sig) {
+		return br, nil
+	}
+
+	data, err := io.ReadAll(br)
+	if err != nil {
+		return nil, fmt.Errorf("reading data: %w", err)
+	}
+
+	plain, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(plain), nil
+}
@@ -0,0 +1,88 @@
+package sii
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+const testUnit = "SiiNunit\n{\neconomy : _nameless.1f4.2ba0 {\n bank: _nameless.1f4.3160\n}\n\n}\n"
+
+func encryptTestData(t *testing.T, plain []byte) []byte {
+	t.Helper()
+
+	zbuf := new(bytes.Buffer)
+	zw := zlib.NewWriter(zbuf)
+	zw.Write(plain) //nolint:errcheck,gosec
+	zw.Close()      //nolint:errcheck,gosec
+
+	payload := zbuf.Bytes()
+	if pad := aes.BlockSize - len(payload)%aes.BlockSize; pad != aes.BlockSize {
+		payload = append(payload, bytes.Repeat([]byte{byte(pad)}, pad)...)
+	}
+
+	block, err := aes.NewCipher(scscKey)
+	if err != nil {
+		t.Fatalf("creating cipher: %s", err)
+	}
+
+	hdr := scscHeader{DataSize: uint32(len(plain))}
+	copy(hdr.Magic[:], scscMagic)
+	copy(hdr.IV[:], "0123456789abcdef")
+
+	cipher.NewCBCEncrypter(block, hdr.IV[:]).CryptBlocks(payload, payload)
+
+	buf := new(bytes.Buffer)
+	if err = binary.Write(buf, binary.LittleEndian, hdr); err != nil {
+		t.Fatalf("writing header: %s", err)
+	}
+	buf.Write(payload)
+
+	return buf.Bytes()
+}
+
+func TestDecrypt(t *testing.T) {
+	enc := encryptTestData(t, []byte(testUnit))
+
+	if !IsEncrypted(enc) {
+		t.Fatal("encrypted data not detected")
+	}
+
+	plain, err := Decrypt(enc)
+	if err != nil {
+		t.Fatalf("decrypting: %s", err)
+	}
+
+	if string(plain) != testUnit {
+		t.Errorf("unexpected plain text: %q", plain)
+	}
+
+	if _, err = Decrypt(enc[:len(enc)-1]); err == nil {
+		t.Error("truncated data did not cause an error")
+	}
+}
+
+func TestNewDecoder(t *testing.T) {
+	for name, input := range map[string][]byte{
+		"encrypted": encryptTestData(t, []byte(testUnit)),
+		"plain":     []byte(testUnit),
+	} {
+		r, err := NewDecoder(bytes.NewReader(input))
+		if err != nil {
+			t.Fatalf("creating decoder for %s: %s", name, err)
+		}
+
+		out, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("reading %s: %s", name, err)
+		}
+
+		if string(out) != testUnit {
+			t.Errorf("unexpected output for %s: %q", name, out)
+		}
+	}
+}